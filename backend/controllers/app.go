@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -16,6 +18,7 @@ func ListApps(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var apps []models.App
 		db.Find(&apps)
+		services.PopulateDependencies(db, apps)
 		c.JSON(http.StatusOK, apps)
 	}
 }
@@ -28,7 +31,22 @@ func CreateApp(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		db.Create(&input)
+		if err := services.ValidateDependencies(db, "", input.DependsOn); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := db.Create(&input).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Failed to create app")
+			return
+		}
+		if err := services.SetDependencies(db, input.ID, input.DependsOn); err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not save app dependencies")
+			return
+		}
+		if input.DependsOn == nil {
+			input.DependsOn = []string{}
+		}
 		c.JSON(http.StatusCreated, input)
 	}
 }
@@ -41,7 +59,9 @@ func GetApp(db *gorm.DB) gin.HandlerFunc {
 			respondWithError(c, http.StatusNotFound, "App not found")
 			return
 		}
-		c.JSON(http.StatusOK, app)
+		apps := []models.App{app}
+		services.PopulateDependencies(db, apps)
+		c.JSON(http.StatusOK, apps[0])
 	}
 }
 
@@ -60,8 +80,26 @@ func UpdateApp(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// A missing dependsOn leaves the dependencies unchanged, [] clears them
+		if input.DependsOn != nil {
+			if err := services.ValidateDependencies(db, app.ID, input.DependsOn); err != nil {
+				respondWithError(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			if err := services.SetDependencies(db, app.ID, input.DependsOn); err != nil {
+				respondWithError(c, http.StatusInternalServerError, "Could not save app dependencies")
+				return
+			}
+		}
+
+		// The app's routes move with its domain, port or server
+		previousServerID := app.ServerID
 		db.Model(&app).Updates(input)
-		c.JSON(http.StatusOK, app)
+		queueProxySyncs(db, previousServerID, app.ServerID)
+
+		apps := []models.App{app}
+		services.PopulateDependencies(db, apps)
+		c.JSON(http.StatusOK, apps[0])
 	}
 }
 
@@ -75,6 +113,8 @@ func DeleteApp(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		db.Delete(&app)
+		services.DeleteDependencies(db, app.ID)
+		queueProxySyncs(db, app.ServerID)
 		c.JSON(http.StatusOK, gin.H{"message": "App deleted successfully"})
 	}
 }
@@ -89,7 +129,8 @@ func StartApp(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var input struct {
-			TimeoutMinutes int `json:"timeout_minutes"`
+			TimeoutMinutes int  `json:"timeout_minutes"`
+			Wait           bool `json:"wait"` // Wait for the health check before responding
 		}
 		if err := c.ShouldBindJSON(&input); err != nil {
 			respondWithError(c, http.StatusBadRequest, err.Error())
@@ -101,37 +142,55 @@ func StartApp(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		out, err := services.StartComposeApp(server, app.ComposePath)
+		// Starting a running app recreates it, which only the reservation holder
+		// and those allowed to stop it may do
+		actor := services.ActorFromContext(db, c)
+		if app.Status == "running" {
+			if err := services.CheckReservationHolder(db, app, actor, isAdmin(c)); err != nil {
+				respondWithError(c, http.StatusForbidden, err.Error())
+				return
+			}
+			if !services.CanStopApp(db, app, actor, isAdmin(c)) {
+				respondWithError(c, http.StatusForbidden, fmt.Sprintf(
+					"%s was started by %s; only they, a project admin or an admin can restart it.",
+					app.Name, app.StartedBy))
+				return
+			}
+		}
+
+		out, err := services.StartApp(db, server, &app, actor, "start_app", input.TimeoutMinutes)
+		if errors.Is(err, services.ErrPortConflict) {
+			respondWithError(c, http.StatusConflict, err.Error())
+			return
+		}
 		if err != nil {
 			respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("Failed to start app: %v", err))
 			return
 		}
 
-		app.Status = "running"
-		now := time.Now()
-		app.StartedAt = &now
-		if input.TimeoutMinutes > 0 {
-			app.AutoStopTimeout = input.TimeoutMinutes
-		} else {
-			app.AutoStopTimeout = 0 // Manual stop
+		message := "App started"
+		if input.Wait && app.HealthStatus == "starting" {
+			app = services.WaitForHealthStatus(db, app.ID)
+			switch app.HealthStatus {
+			case "healthy":
+				message = "App started and healthy"
+			case "unhealthy":
+				message = "App started but failed its health check"
+			}
 		}
 
-		db.Save(&app)
-
-		// Log the action
-		duration := time.Duration(app.AutoStopTimeout) * time.Minute
-		services.LogAppAction(db, c, "start_app", app, &duration)
-
 		timerEndsAt := int64(0)
-		if app.AutoStopTimeout > 0 {
-			timerEndsAt = time.Now().Add(time.Duration(app.AutoStopTimeout) * time.Minute).Unix()
+		if app.TimerEndsAt != nil {
+			timerEndsAt = *app.TimerEndsAt
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"message":       "App started",
-			"output":        out,
-			"timer_ends_at": timerEndsAt,
-			"app_url":       app.AppURL,
+			"message":        message,
+			"output":         out,
+			"timer_ends_at":  timerEndsAt,
+			"app_url":        app.AppURL,
+			"health_status":  app.HealthStatus,
+			"health_message": app.HealthMessage,
 		})
 	}
 }
@@ -151,29 +210,173 @@ func StopApp(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		out, err := services.StopComposeApp(server, app.ComposePath)
-		if err != nil {
-			respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("Failed to stop app: %v", err))
+		// The body is optional; a force stop must give a reason
+		var input struct {
+			Force   bool   `json:"force"`
+			Reason  string `json:"reason"`
+			Cascade bool   `json:"cascade"` // Also stop the running apps that depend on this one
+		}
+		if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+			respondWithError(c, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		// Calculate duration if app was running
-		var duration *time.Duration
-		if app.StartedAt != nil {
-			d := time.Since(*app.StartedAt)
-			duration = &d
+		actor := services.ActorFromContext(db, c)
+		if err := services.CheckReservationHolder(db, app, actor, isAdmin(c)); err != nil {
+			respondWithError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		action := "stop_app"
+		if !services.CanStopApp(db, app, actor, isAdmin(c)) {
+			if !input.Force {
+				respondWithError(c, http.StatusForbidden, fmt.Sprintf(
+					"%s was started by %s; only they, a project admin or an admin can stop it. Use a force stop with a reason to override.",
+					app.Name, app.StartedBy))
+				return
+			}
+			if strings.TrimSpace(input.Reason) == "" {
+				respondWithError(c, http.StatusBadRequest, "A reason is required to force stop an app")
+				return
+			}
+			action = "force_stop_app"
 		}
 
-		app.Status = "stopped"
-		app.StartedAt = nil
-		db.Save(&app)
+		dependents, err := services.RunningDependents(db, app.ID)
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(dependents) > 0 {
+			if !input.Cascade {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":      fmt.Sprintf("%s is required by %s. Stop them first or stop with cascade.", app.Name, services.DependentNames(dependents)),
+					"dependents": dependents,
+				})
+				return
+			}
+			// Cascading must not bypass the reservation and stop policy of the dependents
+			for _, dep := range dependents {
+				if err := services.CheckReservationHolder(db, dep, actor, isAdmin(c)); err != nil {
+					respondWithError(c, http.StatusForbidden, err.Error())
+					return
+				}
+				if !services.CanStopApp(db, dep, actor, isAdmin(c)) && !(input.Force && strings.TrimSpace(input.Reason) != "") {
+					respondWithError(c, http.StatusForbidden, fmt.Sprintf(
+						"Dependent %s was started by %s; only they, a project admin or an admin can stop it.", dep.Name, dep.StartedBy))
+					return
+				}
+			}
+			if err := services.StopDependents(db, app, dependents, actor); err != nil {
+				respondWithError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
 
-		// Log the action
-		services.LogAppAction(db, c, "stop_app", app, duration)
+		out, err := services.StopApp(db, server, &app, actor, action, strings.TrimSpace(input.Reason))
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, fmt.Sprintf("Failed to stop app: %v", err))
+			return
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message": "App stopped",
 			"output":  out,
 		})
 	}
-}
\ No newline at end of file
+}
+
+// RedeployApp pulls new images for a running app and recreates its containers
+func RedeployApp(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+		if app.Status != "running" {
+			respondWithError(c, http.StatusConflict, app.Name+" is not running; start it to deploy the latest images")
+			return
+		}
+
+		server, err := services.GetServerByID(db, app.ServerID)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found for app")
+			return
+		}
+
+		// Redeploying recreates containers, which only the reservation holder may do
+		actor := services.ActorFromContext(db, c)
+		if err := services.CheckReservationHolder(db, app, actor, isAdmin(c)); err != nil {
+			respondWithError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		result, err := services.RedeployApp(db, server, &app, actor)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to redeploy app: %v", err), "output": result.Output})
+			return
+		}
+
+		updated := []string{}
+		for _, service := range result.Services {
+			if service.Changed {
+				updated = append(updated, service.Service)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "App redeployed",
+			"output":   result.Output,
+			"services": result.Services,
+			"updated":  updated,
+		})
+	}
+}
+
+// AppHeartbeat signals that an app is still in use, pushing back its auto-stop timer
+func AppHeartbeat(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		actor := services.ActorFromContext(db, c)
+		if err := services.CheckReservationHolder(db, app, actor, isAdmin(c)); err != nil {
+			respondWithError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		capped, err := services.RecordHeartbeat(db, &app, actor)
+		if errors.Is(err, services.ErrAppNotRunning) {
+			respondWithError(c, http.StatusConflict, "App is not running")
+			return
+		}
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not record heartbeat")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"app":    app,
+			"capped": capped, // The timer reached HEARTBEAT_MAX_HOURS after start
+		})
+	}
+}
+
+// queueProxySyncs regenerates the proxy configuration of the given servers
+func queueProxySyncs(db *gorm.DB, serverIDs ...string) {
+	seen := map[string]bool{}
+	for _, id := range serverIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if server, err := services.GetServerByID(db, id); err == nil {
+			services.QueueProxySync(db, server)
+		}
+	}
+}
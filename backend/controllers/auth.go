@@ -129,4 +129,27 @@ func Register(db *gorm.DB) gin.HandlerFunc {
 
 		c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
 	}
-}
\ No newline at end of file
+}
+
+// currentUser loads the authenticated user of the request
+func currentUser(db *gorm.DB, c *gin.Context) (models.User, bool) {
+	var user models.User
+	username, exists := c.Get("username")
+	if !exists {
+		return user, false
+	}
+	if result := db.Where("username = ?", username).First(&user); result.Error != nil {
+		return user, false
+	}
+	return user, true
+}
+
+// isAdmin reports whether the authenticated user has the global admin role
+func isAdmin(c *gin.Context) bool {
+	claims, exists := c.Get("user")
+	if !exists {
+		return false
+	}
+	mapClaims, ok := claims.(jwt.MapClaims)
+	return ok && mapClaims["role"] == "admin"
+}
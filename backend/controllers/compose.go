@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+// ListComposeFiles returns the compose files found in the app's ComposePath
+func ListComposeFiles(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+		server, err := services.GetServerByID(db, app.ServerID)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found for app")
+			return
+		}
+
+		files, err := services.ListComposeFiles(server, app)
+		if err != nil {
+			respondWithError(c, http.StatusBadGateway, "Could not read compose files: "+err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, files)
+	}
+}
+
+// UpdateComposeFile validates and writes one compose file, keeping a backup of the old one
+func UpdateComposeFile(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+		name := c.Param("file")
+		if !services.IsComposeFileName(name) {
+			respondWithError(c, http.StatusBadRequest, name+" is not a compose file name")
+			return
+		}
+		server, err := services.GetServerByID(db, app.ServerID)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found for app")
+			return
+		}
+
+		var input struct {
+			Content string `json:"content" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		backup, diff, err := services.WriteComposeFile(db, server, app, name, input.Content, services.ActorFromContext(db, c))
+		if errors.Is(err, services.ErrComposeInvalid) {
+			respondWithError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if err != nil {
+			respondWithError(c, http.StatusBadGateway, "Could not write compose file: "+err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Compose file saved",
+			"name":    name,
+			"backup":  backup,
+			"diff":    diff,
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+// ListAppDeployments returns the deployment history of an app, newest first (?limit=, default 50)
+func ListAppDeployments(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit <= 0 || limit > 500 {
+			respondWithError(c, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+
+		var deployments []models.Deployment
+		db.Where("app_id = ?", id).Order("created_at desc").Limit(limit).Find(&deployments)
+		c.JSON(http.StatusOK, deployments)
+	}
+}
+
+// RollbackApp pins an app to the images of an earlier deployment, by default the previous one
+func RollbackApp(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		var input struct {
+			DeploymentID string `json:"deploymentId"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		server, err := services.GetServerByID(db, app.ServerID)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found for app")
+			return
+		}
+
+		// Rolling back recreates containers, which only the reservation holder may do
+		actor := services.ActorFromContext(db, c)
+		if err := services.CheckReservationHolder(db, app, actor, isAdmin(c)); err != nil {
+			respondWithError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		result, err := services.RollbackApp(db, server, &app, input.DeploymentID, actor)
+		switch {
+		case errors.Is(err, services.ErrNoRollbackTarget):
+			respondWithError(c, http.StatusConflict, err.Error())
+			return
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			respondWithError(c, http.StatusNotFound, "Deployment not found")
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to roll back app: %v", err), "output": result.Output})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "App rolled back",
+			"output":     result.Output,
+			"deployment": result.Deployment,
+			"services":   result.Services,
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+type EnvVarInput struct {
+	Value  *string `json:"value"`
+	Secret *bool   `json:"secret"`
+}
+
+// ListAppEnv returns the managed variables of an app with secret values masked
+func ListAppEnv(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		vars, err := services.ListEnvVars(db, id)
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, services.MaskEnvVars(vars))
+	}
+}
+
+func SetAppEnvVar(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		var input EnvVarInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		envVar, err := services.SetEnvVar(db, app, c.Param("key"), input.Value, input.Secret, services.ActorFromContext(db, c))
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, services.MaskEnvVars([]models.AppEnvVar{envVar})[0])
+	}
+}
+
+func DeleteAppEnvVar(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		err := services.DeleteEnvVar(db, app, c.Param("key"), services.ActorFromContext(db, c))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondWithError(c, http.StatusNotFound, "Variable not found")
+			return
+		}
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Variable deleted successfully"})
+	}
+}
+
+// ListAppEnvHistory returns the latest changes to an app's variables (?limit=, default 100)
+func ListAppEnvHistory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit <= 0 || limit > 1000 {
+			respondWithError(c, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+
+		query := db.Where("app_id = ?", id)
+		if key := c.Query("key"); key != "" {
+			query = query.Where("key = ?", key)
+		}
+		var changes []models.AppEnvChange
+		query.Order("created_at desc").Limit(limit).Find(&changes)
+		c.JSON(http.StatusOK, changes)
+	}
+}
+
+// GetAppEnvDiff shows how the next start will change the .env file on the server
+func GetAppEnvDiff(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+		server, err := services.GetServerByID(db, app.ServerID)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found for app")
+			return
+		}
+
+		diff, err := services.EnvDiff(db, server, app)
+		if err != nil {
+			respondWithError(c, http.StatusBadGateway, "Could not read .env from server: "+err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"appId": app.ID, "changes": diff})
+	}
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+type HealthCheckInput struct {
+	URL             string `json:"url" binding:"required"`
+	ExpectedStatus  int    `json:"expectedStatus"`
+	BodyMatch       string `json:"bodyMatch"`
+	TimeoutSeconds  int    `json:"timeoutSeconds"`
+	IntervalSeconds int    `json:"intervalSeconds"`
+	SkipTLSVerify   bool   `json:"skipTlsVerify"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+func GetAppHealthCheck(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var check models.AppHealthCheck
+		if result := db.First(&check, "app_id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "No health check defined for this app")
+			return
+		}
+		c.JSON(http.StatusOK, check)
+	}
+}
+
+// SetAppHealthCheck creates or replaces the health check of an app
+func SetAppHealthCheck(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		var input HealthCheckInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.ContainsAny(input.URL, " \t\n") {
+			respondWithError(c, http.StatusBadRequest, "url must not contain whitespace")
+			return
+		}
+		if input.ExpectedStatus == 0 {
+			input.ExpectedStatus = http.StatusOK
+		}
+		if input.TimeoutSeconds == 0 {
+			input.TimeoutSeconds = 120
+		}
+		if input.IntervalSeconds == 0 {
+			input.IntervalSeconds = 5
+		}
+		if input.ExpectedStatus < 100 || input.ExpectedStatus > 599 || input.TimeoutSeconds < 0 || input.IntervalSeconds < 0 {
+			respondWithError(c, http.StatusBadRequest, "expectedStatus, timeoutSeconds or intervalSeconds out of range")
+			return
+		}
+
+		var check models.AppHealthCheck
+		db.Where("app_id = ?", app.ID).First(&check)
+		check.AppID = app.ID
+		check.URL = input.URL
+		check.ExpectedStatus = input.ExpectedStatus
+		check.BodyMatch = input.BodyMatch
+		check.TimeoutSeconds = input.TimeoutSeconds
+		check.IntervalSeconds = input.IntervalSeconds
+		check.SkipTLSVerify = input.SkipTLSVerify
+		check.Enabled = input.Enabled == nil || *input.Enabled
+
+		if err := db.Save(&check).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not save health check")
+			return
+		}
+
+		// Log the action
+		services.LogAction(db, c, "set_health_check", "app", app.ID, app.Name, "Health check "+services.HealthCheckURL(app, check))
+
+		c.JSON(http.StatusOK, check)
+	}
+}
+
+func DeleteAppHealthCheck(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var check models.AppHealthCheck
+		if result := db.First(&check, "app_id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "No health check defined for this app")
+			return
+		}
+		var app models.App
+		db.First(&app, "id = ?", check.AppID)
+		db.Delete(&check)
+
+		// Log the action
+		services.LogAction(db, c, "delete_health_check", "app", check.AppID, app.Name, "Health check "+services.HealthCheckURL(app, check))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Health check deleted successfully"})
+	}
+}
+
+// RunAppHealthCheck probes the health check of an app once, without changing its stored health
+func RunAppHealthCheck(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+		var check models.AppHealthCheck
+		if result := db.First(&check, "app_id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "No health check defined for this app")
+			return
+		}
+
+		// A single probe: no retries
+		check.TimeoutSeconds = 0
+		url := services.HealthCheckURL(app, check)
+		if err := services.VerifyAppHealth(app, check); err != nil {
+			c.JSON(http.StatusOK, gin.H{"url": url, "healthy": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"url": url, "healthy": true})
+	}
+}
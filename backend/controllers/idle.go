@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+type IdlePolicyInput struct {
+	IdleStopMinutes *int `json:"idleStopMinutes" binding:"required"` // 0 disables idle stops
+}
+
+// SetAppIdlePolicy sets after how many idle minutes a running app is stopped
+func SetAppIdlePolicy(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		var input IdlePolicyInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if *input.IdleStopMinutes < 0 {
+			respondWithError(c, http.StatusBadRequest, "idleStopMinutes must not be negative")
+			return
+		}
+
+		// A changed policy starts over, cancelling any pending idle stop
+		updates := map[string]interface{}{"idle_stop_minutes": *input.IdleStopMinutes, "idle_warned_at": nil}
+		if err := db.Model(&app).Updates(updates).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not save idle policy")
+			return
+		}
+
+		// Log the action
+		details := "Idle stop disabled"
+		if *input.IdleStopMinutes > 0 {
+			details = fmt.Sprintf("Stop after %d idle minutes", *input.IdleStopMinutes)
+		}
+		services.LogAction(db, c, "set_idle_policy", "app", app.ID, app.Name, details)
+
+		c.JSON(http.StatusOK, app)
+	}
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+// GetAppLogs returns container logs of an app. Query parameters:
+// services (comma-separated), tail (default 200, or "all"), since, timestamps.
+// With follow=true the logs are streamed as server-sent "log" events.
+func GetAppLogs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+		server, err := services.GetServerByID(db, app.ServerID)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found for app")
+			return
+		}
+
+		opts := services.LogOptions{
+			Tail:       c.DefaultQuery("tail", "200"),
+			Since:      c.Query("since"),
+			Timestamps: c.Query("timestamps") == "true",
+		}
+		if list := c.Query("services"); list != "" {
+			opts.Services = strings.Split(list, ",")
+		}
+		if err := opts.Validate(); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if c.Query("follow") != "true" {
+			logs, err := services.AppLogs(server, app, opts)
+			if err != nil {
+				respondWithError(c, http.StatusBadGateway, "Could not read logs: "+err.Error())
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"appId": app.ID, "logs": logs})
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		err = services.FollowAppLogs(c.Request.Context(), server, app, opts, func(line string) {
+			c.SSEvent("log", line)
+			c.Writer.Flush()
+		})
+		if err != nil {
+			c.SSEvent("error", err.Error())
+		} else {
+			c.SSEvent("end", "")
+		}
+		c.Writer.Flush()
+	}
+}
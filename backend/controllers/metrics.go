@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+// maxMetricPoints caps the number of buckets a single series can return
+const maxMetricPoints = 1000
+
+func GetServerMetrics(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var server models.Server
+		if result := db.First(&server, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found")
+			return
+		}
+		respondWithMetricSeries(c, db, "server", server.ID, services.ServerMetricNames)
+	}
+}
+
+func GetAppMetrics(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+		respondWithMetricSeries(c, db, "app", app.ID, services.AppMetricNames)
+	}
+}
+
+// respondWithMetricSeries handles the shared ?metric=&from=&to=&step= query parameters
+func respondWithMetricSeries(c *gin.Context, db *gorm.DB, resourceType, resourceID string, available []string) {
+	now := time.Now()
+
+	to, err := parseTimeParam(c.Query("to"), now)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "Invalid 'to': "+err.Error())
+		return
+	}
+	from, err := parseTimeParam(c.Query("from"), to.Add(-time.Hour))
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "Invalid 'from': "+err.Error())
+		return
+	}
+	if !from.Before(to) {
+		respondWithError(c, http.StatusBadRequest, "'from' must be before 'to'")
+		return
+	}
+
+	metrics := available
+	if param := c.Query("metric"); param != "" {
+		metrics = strings.Split(param, ",")
+		for _, metric := range metrics {
+			if !slices.Contains(available, metric) {
+				respondWithError(c, http.StatusBadRequest, fmt.Sprintf("Unknown metric %q", metric))
+				return
+			}
+		}
+	}
+
+	// Default to roughly 300 points, never finer than the stored resolution
+	// and never more than maxMetricPoints buckets
+	rangeSeconds := int(to.Sub(from).Seconds())
+	step := rangeSeconds / 300
+	if param := c.Query("step"); param != "" {
+		if step, err = strconv.Atoi(param); err != nil || step <= 0 {
+			respondWithError(c, http.StatusBadRequest, "Invalid 'step'")
+			return
+		}
+	}
+	if minStep := services.MinimumStep(from, now); step < minStep {
+		step = minStep
+	}
+	if minStep := (rangeSeconds + maxMetricPoints - 1) / maxMetricPoints; step < minStep {
+		step = minStep
+	}
+
+	series, err := services.QueryMetricSeries(db, resourceType, resourceID, metrics, from, to, step)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, "Could not fetch metrics")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resourceType": resourceType,
+		"resourceId":   resourceID,
+		"from":         from.Unix(),
+		"to":           to.Unix(),
+		"step":         step,
+		"series":       series,
+	})
+}
+
+// parseTimeParam accepts unix seconds or RFC3339 and falls back when empty
+func parseTimeParam(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+type NotificationPreferencesInput struct {
+	AppExpiring   *bool `json:"appExpiring"`
+	StartFailed   *bool `json:"startFailed"`
+	ServerOffline *bool `json:"serverOffline"`
+}
+
+func GetNotificationPreferences(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(db, c)
+		if !ok {
+			respondWithError(c, http.StatusUnauthorized, "User not found")
+			return
+		}
+
+		// Users without a row have not opted in to anything
+		prefs := models.NotificationPreference{UserID: user.ID}
+		db.Where("user_id = ?", user.ID).Find(&prefs)
+		c.JSON(http.StatusOK, prefs)
+	}
+}
+
+func UpdateNotificationPreferences(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(db, c)
+		if !ok {
+			respondWithError(c, http.StatusUnauthorized, "User not found")
+			return
+		}
+
+		var input NotificationPreferencesInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		prefs := models.NotificationPreference{UserID: user.ID}
+		db.Where("user_id = ?", user.ID).Find(&prefs)
+
+		// Update fields
+		if input.AppExpiring != nil {
+			prefs.AppExpiring = *input.AppExpiring
+		}
+		if input.StartFailed != nil {
+			prefs.StartFailed = *input.StartFailed
+		}
+		if input.ServerOffline != nil {
+			prefs.ServerOffline = *input.ServerOffline
+		}
+
+		if err := db.Save(&prefs).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not update notification preferences")
+			return
+		}
+		c.JSON(http.StatusOK, prefs)
+	}
+}
+
+// SendTestNotification emails a test message to the requesting admin
+func SendTestNotification(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(db, c)
+		if !ok {
+			respondWithError(c, http.StatusUnauthorized, "User not found")
+			return
+		}
+		if !services.MailEnabled() {
+			respondWithError(c, http.StatusBadRequest, "SMTP is not configured, set SMTP_HOST")
+			return
+		}
+
+		if err := services.SendTestNotification(user); err != nil {
+			respondWithError(c, http.StatusBadGateway, fmt.Sprintf("Failed to send test email: %v", err))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Test email sent to " + user.Email})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"gorm.io/gorm"
 
 	"backend/models"
+	"backend/services"
 )
 
 func ListProjects(db *gorm.DB) gin.HandlerFunc {
@@ -75,3 +76,68 @@ func DeleteProject(db *gorm.DB) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
 	}
 }
+
+func ListProjectMembers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var members []models.ProjectMember
+		db.Where("project_id = ?", id).Find(&members)
+		c.JSON(http.StatusOK, members)
+	}
+}
+
+// SetProjectMember adds a user to a project or changes their role
+func SetProjectMember(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var project models.Project
+		if result := db.First(&project, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Project not found")
+			return
+		}
+		var user models.User
+		if result := db.First(&user, "id = ?", c.Param("userId")); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "User not found")
+			return
+		}
+
+		var input struct {
+			Role string `json:"role" binding:"required,oneof=admin member"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		member := models.ProjectMember{ProjectID: project.ID, UserID: user.ID, Role: input.Role}
+		if err := db.Save(&member).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not save project member")
+			return
+		}
+
+		// Log the action
+		services.LogAction(db, c, "set_project_member", "project", project.ID, project.Name, user.Username+" is now "+input.Role)
+
+		c.JSON(http.StatusOK, member)
+	}
+}
+
+func RemoveProjectMember(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var member models.ProjectMember
+		if result := db.First(&member, "project_id = ? AND user_id = ?", id, c.Param("userId")); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Project member not found")
+			return
+		}
+
+		db.Delete(&member)
+
+		// Log the action
+		var project models.Project
+		db.First(&project, "id = ?", id)
+		services.LogAction(db, c, "remove_project_member", "project", id, project.Name, "Removed user "+member.UserID)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Project member removed"})
+	}
+}
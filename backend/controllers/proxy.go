@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+// SleepingPage serves the "environment sleeping" page for the app whose
+// Domain matches the request host. Traefik routes stopped apps here.
+func SleepingPage(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		host := c.Request.Host
+		if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+			host = forwarded
+		}
+
+		var app models.App
+		if result := db.First(&app, "domain = ?", stripPort(host)); result.Error != nil {
+			app.Name = "This environment"
+		}
+		c.Header("Retry-After", "60")
+		c.Data(http.StatusServiceUnavailable, "text/html; charset=utf-8", []byte(services.RenderSleepingPage(app)))
+	}
+}
+
+// stripPort removes a :port suffix from a host
+func stripPort(host string) string {
+	for i := len(host) - 1; i >= 0; i-- {
+		switch host[i] {
+		case ':':
+			return host[:i]
+		case ']':
+			return host
+		}
+	}
+	return host
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// ListRecordings returns terminal session recordings, newest first.
+// Filters: resourceId, userId, limit (default 100).
+func ListRecordings(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit <= 0 || limit > 1000 {
+			respondWithError(c, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+
+		query := db.Model(&models.SessionRecording{})
+		if resourceID := c.Query("resourceId"); resourceID != "" {
+			query = query.Where("resource_id = ?", resourceID)
+		}
+		if userID := c.Query("userId"); userID != "" {
+			query = query.Where("user_id = ?", userID)
+		}
+
+		var recordings []models.SessionRecording
+		query.Order("started_at desc").Limit(limit).Find(&recordings)
+		c.JSON(http.StatusOK, recordings)
+	}
+}
+
+// DownloadRecording serves a recording as an asciicast v2 file, playable with asciinema
+func DownloadRecording(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var recording models.SessionRecording
+		if result := db.First(&recording, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Recording not found")
+			return
+		}
+		serveRecording(c, recording)
+	}
+}
+
+// GetAuditLogRecording serves the recording of the terminal session started by an audit entry
+func GetAuditLogRecording(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var recording models.SessionRecording
+		if result := db.First(&recording, "audit_log_id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "No recording for this audit log entry")
+			return
+		}
+		serveRecording(c, recording)
+	}
+}
+
+func serveRecording(c *gin.Context, recording models.SessionRecording) {
+	c.Header("Content-Type", "application/x-asciicast")
+	c.FileAttachment(recording.Path, recording.ID+".cast")
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+type ReservationInput struct {
+	StartsAt  time.Time `json:"startsAt" binding:"required"`
+	EndsAt    time.Time `json:"endsAt" binding:"required"`
+	Reason    string    `json:"reason" binding:"required"`
+	AutoStart bool      `json:"autoStart"`
+	AutoStop  bool      `json:"autoStop"`
+}
+
+// ListAppReservations returns reservations overlapping ?from=&to= (default: from now on)
+func ListAppReservations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		from, err := parseTimeParam(c.Query("from"), time.Now())
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, "Invalid 'from': "+err.Error())
+			return
+		}
+		query := db.Where("app_id = ? AND ends_at > ?", id, from)
+		if c.Query("to") != "" {
+			to, err := parseTimeParam(c.Query("to"), time.Time{})
+			if err != nil {
+				respondWithError(c, http.StatusBadRequest, "Invalid 'to': "+err.Error())
+				return
+			}
+			query = query.Where("starts_at < ?", to)
+		}
+
+		var reservations []models.Reservation
+		query.Order("starts_at").Find(&reservations)
+		c.JSON(http.StatusOK, reservations)
+	}
+}
+
+func CreateReservation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		user, ok := currentUser(db, c)
+		if !ok {
+			respondWithError(c, http.StatusUnauthorized, "User not found")
+			return
+		}
+
+		var input ReservationInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !input.EndsAt.After(input.StartsAt) {
+			respondWithError(c, http.StatusBadRequest, "endsAt must be after startsAt")
+			return
+		}
+		if !input.EndsAt.After(time.Now()) {
+			respondWithError(c, http.StatusBadRequest, "Reservation window is already over")
+			return
+		}
+
+		reservation := models.Reservation{
+			AppID:     app.ID,
+			UserID:    user.ID,
+			Username:  user.Username,
+			StartsAt:  input.StartsAt,
+			EndsAt:    input.EndsAt,
+			Reason:    input.Reason,
+			AutoStart: input.AutoStart,
+			AutoStop:  input.AutoStop,
+		}
+
+		conflict, err := services.CreateReservation(db, &reservation)
+		if errors.Is(err, services.ErrReservationConflict) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("%s is already reserved by %s from %s to %s",
+					app.Name, conflict.Username, conflict.StartsAt.Format(time.RFC3339), conflict.EndsAt.Format(time.RFC3339)),
+				"conflict": conflict,
+			})
+			return
+		}
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not create reservation")
+			return
+		}
+
+		// Log the action
+		services.LogAction(db, c, "create_reservation", "app", app.ID, app.Name,
+			fmt.Sprintf("Reserved from %s to %s: %s", reservation.StartsAt.Format(time.RFC3339), reservation.EndsAt.Format(time.RFC3339), reservation.Reason))
+
+		c.JSON(http.StatusCreated, reservation)
+	}
+}
+
+// DeleteReservation cancels a reservation; only its holder or an admin may do so
+func DeleteReservation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var reservation models.Reservation
+		if result := db.First(&reservation, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Reservation not found")
+			return
+		}
+
+		user, ok := currentUser(db, c)
+		if !ok {
+			respondWithError(c, http.StatusUnauthorized, "User not found")
+			return
+		}
+		if reservation.UserID != user.ID && !isAdmin(c) {
+			respondWithError(c, http.StatusForbidden, "Only the holder or an admin can cancel this reservation")
+			return
+		}
+
+		db.Delete(&reservation)
+
+		// Log the action
+		var app models.App
+		db.First(&app, "id = ?", reservation.AppID)
+		services.LogAction(db, c, "cancel_reservation", "app", reservation.AppID, app.Name,
+			fmt.Sprintf("Cancelled reservation of %s from %s to %s", reservation.Username,
+				reservation.StartsAt.Format(time.RFC3339), reservation.EndsAt.Format(time.RFC3339)))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Reservation cancelled"})
+	}
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+type ScheduleInput struct {
+	Cron            string `json:"cron"`
+	Timezone        string `json:"timezone"`
+	Action          string `json:"action"`
+	AutoStopMinutes *int   `json:"autoStopMinutes"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+// apply copies the set fields of the input onto a schedule and validates the result
+func (input ScheduleInput) apply(schedule *models.AppSchedule) (string, bool) {
+	if input.Cron != "" {
+		schedule.CronExpr = input.Cron
+	}
+	if input.Timezone != "" {
+		schedule.Timezone = input.Timezone
+	}
+	if input.Action != "" {
+		schedule.Action = input.Action
+	}
+	if input.AutoStopMinutes != nil {
+		schedule.AutoStopMinutes = *input.AutoStopMinutes
+	}
+	if input.Enabled != nil {
+		schedule.Enabled = *input.Enabled
+	}
+
+	if schedule.Action != "start" && schedule.Action != "stop" {
+		return "action must be 'start' or 'stop'", false
+	}
+	if schedule.AutoStopMinutes < 0 {
+		return "autoStopMinutes must not be negative", false
+	}
+	if _, _, err := services.ParseSchedule(schedule.CronExpr, schedule.Timezone); err != nil {
+		return err.Error(), false
+	}
+	if err := services.SetNextRun(schedule, time.Now()); err != nil {
+		return err.Error(), false
+	}
+	return "", true
+}
+
+func ListAppSchedules(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var schedules []models.AppSchedule
+		db.Where("app_id = ?", id).Order("created_at").Find(&schedules)
+		c.JSON(http.StatusOK, schedules)
+	}
+}
+
+func CreateAppSchedule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		var input ScheduleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if input.Cron == "" {
+			respondWithError(c, http.StatusBadRequest, "cron is required")
+			return
+		}
+
+		schedule := models.AppSchedule{AppID: app.ID, Timezone: "UTC", Enabled: true}
+		if message, ok := input.apply(&schedule); !ok {
+			respondWithError(c, http.StatusBadRequest, message)
+			return
+		}
+
+		if err := db.Create(&schedule).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not create schedule")
+			return
+		}
+
+		// Log the action
+		services.LogAction(db, c, "create_schedule", "app", app.ID, app.Name,
+			"Schedule "+schedule.Action+" at '"+schedule.CronExpr+"' ("+schedule.Timezone+")")
+
+		c.JSON(http.StatusCreated, schedule)
+	}
+}
+
+func UpdateSchedule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var schedule models.AppSchedule
+		if result := db.First(&schedule, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Schedule not found")
+			return
+		}
+
+		var input ScheduleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if message, ok := input.apply(&schedule); !ok {
+			respondWithError(c, http.StatusBadRequest, message)
+			return
+		}
+
+		if err := db.Save(&schedule).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not update schedule")
+			return
+		}
+
+		// Log the action
+		var app models.App
+		db.First(&app, "id = ?", schedule.AppID)
+		details := "Schedule " + schedule.Action + " at '" + schedule.CronExpr + "' (" + schedule.Timezone + ")"
+		if !schedule.Enabled {
+			details += ", disabled"
+		}
+		services.LogAction(db, c, "update_schedule", "app", schedule.AppID, app.Name, details)
+
+		c.JSON(http.StatusOK, schedule)
+	}
+}
+
+func DeleteSchedule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var schedule models.AppSchedule
+		if result := db.First(&schedule, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Schedule not found")
+			return
+		}
+
+		var app models.App
+		db.First(&app, "id = ?", schedule.AppID)
+		db.Delete(&schedule)
+
+		// Log the action
+		services.LogAction(db, c, "delete_schedule", "app", schedule.AppID, app.Name,
+			"Schedule "+schedule.Action+" at '"+schedule.CronExpr+"' ("+schedule.Timezone+")")
+
+		c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully"})
+	}
+}
+
+// PreviewSchedule lists the next ?count= firings of a schedule (default 5, max 100)
+func PreviewSchedule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var schedule models.AppSchedule
+		if result := db.First(&schedule, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Schedule not found")
+			return
+		}
+
+		count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+		if err != nil || count <= 0 || count > 100 {
+			respondWithError(c, http.StatusBadRequest, "count must be between 1 and 100")
+			return
+		}
+
+		runs, err := services.NextRuns(schedule, time.Now(), count)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"scheduleId": schedule.ID,
+			"cron":       schedule.CronExpr,
+			"timezone":   schedule.Timezone,
+			"action":     schedule.Action,
+			"enabled":    schedule.Enabled,
+			"nextRuns":   runs,
+		})
+	}
+}
@@ -25,6 +25,10 @@ func CreateServer(db *gorm.DB) gin.HandlerFunc {
 			respondWithError(c, http.StatusBadRequest, err.Error())
 			return
 		}
+		if input.ProxyType != "" && input.ProxyType != "nginx" && input.ProxyType != "traefik" {
+			respondWithError(c, http.StatusBadRequest, "proxyType must be 'nginx', 'traefik' or empty")
+			return
+		}
 
 		// Test server connectivity before creating
 		services.UpdateServerStatus(&input)
@@ -64,6 +68,10 @@ func UpdateServer(db *gorm.DB) gin.HandlerFunc {
 			respondWithError(c, http.StatusBadRequest, err.Error())
 			return
 		}
+		if input.ProxyType != "" && input.ProxyType != "nginx" && input.ProxyType != "traefik" {
+			respondWithError(c, http.StatusBadRequest, "proxyType must be 'nginx', 'traefik' or empty")
+			return
+		}
 
 		db.Model(&server).Updates(input)
 		c.JSON(http.StatusOK, server)
@@ -93,16 +101,17 @@ func TestServerConnection(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Test the connection and update status
-		services.UpdateServerStatus(&server)
-		
-		// Save the updated status to database
-		db.Save(&server)
+		// Test the connection, save the updated status and record metrics
+		if err := services.RefreshServer(db, &server); err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Failed to save server status")
+			return
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"status":           server.Status,
 			"runningAppsCount": server.RunningAppsCount,
 			"lastChecked":      server.LastChecked,
+			"metrics":          server.Metrics,
 		})
 	}
 }
@@ -113,8 +122,7 @@ func RefreshAllServers(db *gorm.DB) gin.HandlerFunc {
 		db.Find(&servers)
 
 		for i := range servers {
-			services.UpdateServerStatus(&servers[i])
-			db.Save(&servers[i])
+			services.RefreshServer(db, &servers[i])
 		}
 
 		c.JSON(http.StatusOK, gin.H{
@@ -123,3 +131,53 @@ func RefreshAllServers(db *gorm.DB) gin.HandlerFunc {
 		})
 	}
 }
+
+// GetProxyConfig previews the reverse-proxy configuration generated for a server's apps
+func GetProxyConfig(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		server, err := services.GetServerByID(db, id)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found")
+			return
+		}
+
+		config, err := services.RenderProxyConfig(db, server)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"proxyType": server.ProxyType,
+			"path":      services.ProxyConfigPath(server),
+			"config":    config,
+		})
+	}
+}
+
+// SyncProxyConfig pushes the generated proxy configuration to a server and reloads the proxy
+func SyncProxyConfig(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		server, err := services.GetServerByID(db, id)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found")
+			return
+		}
+		if server.ProxyType == "" {
+			respondWithError(c, http.StatusBadRequest, "Server has no proxy type configured")
+			return
+		}
+
+		out, err := services.SyncProxyConfig(db, server)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "output": out})
+			return
+		}
+
+		// Log the action
+		services.LogAction(db, c, "sync_proxy_config", "server", server.ID, server.Name, "Wrote "+services.ProxyConfigPath(server))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Proxy configuration synced", "output": out})
+	}
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+// terminalUpgrader only accepts WebSockets from the dashboard origins
+// (TERMINAL_ALLOWED_ORIGINS, comma-separated)
+var terminalUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		allowed := os.Getenv("TERMINAL_ALLOWED_ORIGINS")
+		if allowed == "" {
+			allowed = "http://localhost:3000,http://frontend:80"
+		}
+		return slices.Contains(strings.Split(allowed, ","), origin)
+	},
+}
+
+// terminalMessage is a message sent by the browser: keyboard input or a resize
+type terminalMessage struct {
+	Type string `json:"type"` // "input", "resize"
+	Data string `json:"data"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+// AppTerminal opens a shell in a service container of an app over a WebSocket
+// (?service=, &cols=, &rows=). Only admins and project admins may use it.
+func AppTerminal(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var app models.App
+		if result := db.First(&app, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "App not found")
+			return
+		}
+
+		actor := services.ActorFromContext(db, c)
+		if !isAdmin(c) && !services.IsProjectAdmin(db, app.ProjectID, actor.UserID) {
+			respondWithError(c, http.StatusForbidden, "Only admins and project admins can open a terminal")
+			return
+		}
+		if app.Status != "running" {
+			respondWithError(c, http.StatusConflict, app.Name+" is not running")
+			return
+		}
+
+		service := c.Query("service")
+		command, err := services.ContainerShellCommand(app, service)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		server, err := services.GetServerByID(db, app.ServerID)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found for app")
+			return
+		}
+
+		serveTerminal(c, db, actor, server, command, "app", app.ID, app.Name, "Shell in service "+service)
+	}
+}
+
+// ServerTerminal opens a shell on a server's host over a WebSocket (admins only)
+func ServerTerminal(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		server, err := services.GetServerByID(db, id)
+		if err != nil {
+			respondWithError(c, http.StatusNotFound, "Server not found")
+			return
+		}
+		actor := services.ActorFromContext(db, c)
+		serveTerminal(c, db, actor, server, "", "server", server.ID, server.Name, "Host shell")
+	}
+}
+
+// serveTerminal connects a WebSocket to an SSH PTY session running command.
+// Output is sent as binary messages; the session is audited when it starts and
+// ends and recorded in asciicast format.
+func serveTerminal(c *gin.Context, db *gorm.DB, actor services.Actor, server models.Server, command, resourceType, resourceID, resourceName, target string) {
+	cols, _ := strconv.Atoi(c.DefaultQuery("cols", "80"))
+	rows, _ := strconv.Atoi(c.DefaultQuery("rows", "24"))
+
+	conn, err := terminalUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	terminal, err := services.OpenTerminal(server, command, cols, rows)
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte("Could not open terminal: "+err.Error()+"\r\n"))
+		return
+	}
+	defer terminal.Close()
+
+	// Sessions are only allowed while they can be recorded
+	start, err := services.CreateAuditLog(db, actor, "terminal_session_start", resourceType, resourceID, resourceName, target)
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte("Could not audit terminal session\r\n"))
+		return
+	}
+	recorder, err := services.StartRecording(db, start, resourceName+": "+target, cols, rows)
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte("Could not record terminal session: "+err.Error()+"\r\n"))
+		return
+	}
+
+	reason := "closed by client"
+	defer func() {
+		recorder.Close()
+		details := fmt.Sprintf("%s, Started: %s, Duration: %s, Reason: %s, Recording: %s",
+			target, terminal.Started.Format(time.RFC3339), time.Since(terminal.Started).Round(time.Second), reason, recorder.ID())
+		services.LogActionAs(db, actor, "terminal_session_end", resourceType, resourceID, resourceName, details)
+	}()
+
+	// Remote output -> browser; the connection is closed when the shell exits
+	shellDone := make(chan struct{})
+	go func() {
+		defer conn.Close()
+		buf := make([]byte, 8192)
+		for {
+			n, err := terminal.Output.Read(buf)
+			if n > 0 {
+				recorder.Output(buf[:n])
+				if conn.WriteMessage(websocket.BinaryMessage, buf[:n]) != nil {
+					return
+				}
+			}
+			if err != nil {
+				close(shellDone)
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "shell exited"), time.Now().Add(time.Second))
+				return
+			}
+		}
+	}()
+
+	// Browser -> remote input; no input within the idle timeout ends the session
+	idle := services.TerminalIdleTimeout()
+	for {
+		conn.SetReadDeadline(time.Now().Add(idle))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-shellDone:
+				reason = "shell exited"
+			default:
+				var netErr interface{ Timeout() bool }
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					reason = "idle timeout"
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseNormalClosure, "idle timeout"), time.Now().Add(time.Second))
+				}
+			}
+			return
+		}
+
+		var msg terminalMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case "input":
+			recorder.Input([]byte(msg.Data))
+			terminal.Write([]byte(msg.Data))
+		case "resize":
+			if msg.Cols > 0 && msg.Rows > 0 {
+				recorder.Resize(msg.Cols, msg.Rows)
+				terminal.Resize(msg.Cols, msg.Rows)
+			}
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -161,14 +164,16 @@ func GetAuditLogs(db *gorm.DB) gin.HandlerFunc {
 		// Parse query parameters
 		limitStr := c.DefaultQuery("limit", "50")
 		offsetStr := c.DefaultQuery("offset", "0")
-		userID := c.Query("userId")
-		action := c.Query("action")
-		resourceType := c.Query("resourceType")
+		filter, err := auditLogFilter(c)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		limit, _ := strconv.Atoi(limitStr)
 		offset, _ := strconv.Atoi(offsetStr)
 
-		logs, total, err := services.GetAuditLogs(db, limit, offset, userID, action, resourceType)
+		logs, total, err := services.GetAuditLogs(db, limit, offset, filter)
 		if err != nil {
 			respondWithError(c, http.StatusInternalServerError, "Could not fetch audit logs")
 			return
@@ -181,4 +186,95 @@ func GetAuditLogs(db *gorm.DB) gin.HandlerFunc {
 			"offset": offset,
 		})
 	}
-}
\ No newline at end of file
+}
+
+// ExportAuditLogs streams the filtered audit log as a CSV or NDJSON download
+func ExportAuditLogs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := auditLogFilter(c)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		format := c.DefaultQuery("format", "csv")
+		contentType, ok := services.AuditExportFormats[format]
+		if !ok {
+			respondWithError(c, http.StatusBadRequest, "format must be 'csv' or 'ndjson'")
+			return
+		}
+
+		// Log the action before streaming so failed and aborted exports are
+		// audited too; resource IDs are UUIDs, so it is logged against the user
+		actor := services.ActorFromContext(db, c)
+		if err := services.LogActionAs(db, actor, "export_audit_logs", "user", actor.UserID, actor.Username,
+			fmt.Sprintf("Exported audit log as %s (%s)", format, filter)); err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not audit the export")
+			return
+		}
+
+		filename := fmt.Sprintf("audit-log-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+
+		count, err := services.ExportAuditLogs(db, c.Writer, format, filter)
+		if err != nil {
+			// Headers are sent, so a failed export can only be cut short
+			log.Printf("Audit log export by %s failed after %d entries: %v", actor.Username, count, err)
+			c.Abort()
+		}
+	}
+}
+
+// GetAuditArchiveStatus reports the audit log retention policy and its archives
+func GetAuditArchiveStatus(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := services.GetAuditArchiveStatus(db)
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not read audit archive status")
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// VerifyAuditLogs walks the audit log hash chain and reports the first broken link
+func VerifyAuditLogs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := services.VerifyAuditChain(db)
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not verify audit log")
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// auditLogFilter reads the audit log filters from the query string; from and
+// to are RFC 3339 or Unix seconds
+func auditLogFilter(c *gin.Context) (services.AuditLogFilter, error) {
+	filter := services.AuditLogFilter{
+		UserID:       c.Query("userId"),
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resourceType"),
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := parseTimeParam(value, time.Time{})
+		if err != nil {
+			return filter, fmt.Errorf("invalid from: %v", err)
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseTimeParam(value, time.Time{})
+		if err != nil {
+			return filter, fmt.Errorf("invalid to: %v", err)
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+	return filter, nil
+}
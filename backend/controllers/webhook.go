@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+type WebhookInput struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Events string `json:"events"`
+	Secret string `json:"secret"`
+	Active *bool  `json:"active"`
+}
+
+// validate checks the URL and event filter of a webhook input
+func (input WebhookInput) validate() error {
+	if input.URL != "" {
+		u, err := url.Parse(input.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an absolute http or https URL")
+		}
+	}
+	if input.Events != "" {
+		for _, event := range strings.Split(input.Events, ",") {
+			event = strings.TrimSpace(event)
+			if event != "*" && !slices.Contains(services.WebhookEvents, event) {
+				return fmt.Errorf("unknown event %q, expected one of %s or *", event, strings.Join(services.WebhookEvents, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func ListWebhooks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var webhooks []models.Webhook
+		db.Find(&webhooks)
+		c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+func CreateWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input WebhookInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if input.Name == "" || input.URL == "" {
+			respondWithError(c, http.StatusBadRequest, "name and url are required")
+			return
+		}
+		if err := input.validate(); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		webhook := models.Webhook{
+			Name:   input.Name,
+			URL:    input.URL,
+			Events: input.Events,
+			Secret: input.Secret,
+			Active: true,
+		}
+		if webhook.Events == "" {
+			webhook.Events = "*"
+		}
+		if input.Active != nil {
+			webhook.Active = *input.Active
+		}
+
+		if err := db.Create(&webhook).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not create webhook")
+			return
+		}
+
+		// Log the action
+		services.LogAction(db, c, "create_webhook", "webhook", webhook.ID, webhook.Name, "Webhook created for "+webhook.Events)
+
+		c.JSON(http.StatusCreated, webhook)
+	}
+}
+
+func UpdateWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var webhook models.Webhook
+		if result := db.First(&webhook, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		var input WebhookInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := input.validate(); err != nil {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Update fields
+		if input.Name != "" {
+			webhook.Name = input.Name
+		}
+		if input.URL != "" {
+			webhook.URL = input.URL
+		}
+		if input.Events != "" {
+			webhook.Events = input.Events
+		}
+		if input.Secret != "" {
+			webhook.Secret = input.Secret
+		}
+		if input.Active != nil {
+			webhook.Active = *input.Active
+		}
+
+		if err := db.Save(&webhook).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not update webhook")
+			return
+		}
+
+		// Log the action
+		services.LogAction(db, c, "update_webhook", "webhook", webhook.ID, webhook.Name, "Webhook updated")
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+func DeleteWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var webhook models.Webhook
+		if result := db.First(&webhook, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		if err := db.Delete(&webhook).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not delete webhook")
+			return
+		}
+
+		// Log the action
+		services.LogAction(db, c, "delete_webhook", "webhook", webhook.ID, webhook.Name, "Webhook deleted")
+
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+	}
+}
+
+func ListWebhookDeliveries(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var webhook models.Webhook
+		if result := db.First(&webhook, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+		var deliveries []models.WebhookDelivery
+		var total int64
+		query := db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID)
+		query.Count(&total)
+		if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+			respondWithError(c, http.StatusInternalServerError, "Could not fetch deliveries")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"deliveries": deliveries,
+			"total":      total,
+			"limit":      limit,
+			"offset":     offset,
+		})
+	}
+}
+
+func TestWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var webhook models.Webhook
+		if result := db.First(&webhook, "id = ?", id); result.Error != nil {
+			respondWithError(c, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		delivery := services.SendTestEvent(db, webhook)
+		c.JSON(http.StatusOK, delivery)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/joho/godotenv"
 	"backend/migrations"
 	"backend/models"
+	"backend/proxy"
 	"backend/router"
+	"backend/services"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -46,13 +48,35 @@ func main() {
 	}
 
 	// Auto-migrate models
-	db.AutoMigrate(&models.User{}, &models.Server{}, &models.Project{}, &models.App{}, &models.AuditLog{})
+	db.AutoMigrate(&models.User{}, &models.Server{}, &models.Project{}, &models.App{}, &models.AuditLog{}, &models.MetricSample{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.NotificationPreference{}, &models.AppSchedule{}, &models.Reservation{}, &models.ProjectMember{}, &models.AppDependency{}, &models.AppEnvVar{}, &models.AppEnvChange{}, &models.Deployment{}, &models.SessionRecording{}, &models.AppHealthCheck{})
 
 	// Run migrations
 	if err := migrations.CreateDefaultUsers(db); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// `backend verify-audit` checks the audit log hash chain and exits. It runs
+	// before the backfill so it sees the audit log exactly as it was found.
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		verifyAudit(db)
+		return
+	}
+
+	if err := services.BackfillAuditChain(db); err != nil {
+		log.Fatalf("Failed to hash chain existing audit log entries: %v", err)
+	}
+
+	// Start background workers: job queue, auto-stop, schedules, metrics collection, recording and audit log retention
+	services.StartJobWorkers(4)
+	services.StartAutoStopSweeper(db)
+	services.StartScheduler(db)
+	services.StartMetricsCollector(db)
+	services.StartRecordingCleanup(db)
+	services.StartAuditArchiver(db)
+
+	// Built-in scale-to-zero proxy, enabled by PROXY_LISTEN_ADDR
+	proxy.Start(db)
+
 	// Set Gin to production mode in production
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -70,3 +94,17 @@ func main() {
 	log.Printf("Server starting on port %s", port)
 	log.Fatal(r.Run(":" + port))
 }
+
+func verifyAudit(db *gorm.DB) {
+	report, err := services.VerifyAuditChain(db)
+	if err != nil {
+		log.Fatalf("Could not verify the audit log: %v", err)
+	}
+	if !report.Valid {
+		if report.BrokenID != "" {
+			log.Fatalf("Audit log chain broken at sequence %d (entry %s): %s", report.BrokenAt, report.BrokenID, report.Reason)
+		}
+		log.Fatalf("Audit log chain incomplete: %s", report.Reason)
+	}
+	log.Printf("Audit log chain intact: %d entries, sequence %d to %d", report.Checked, report.FirstSequence, report.LastSequence)
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// dbCollector reports gauges that are already tracked in the database
+type dbCollector struct {
+	db           *gorm.DB
+	runningApps  *prometheus.Desc
+	serverOnline *prometheus.Desc
+}
+
+func newDBCollector(db *gorm.DB) *dbCollector {
+	return &dbCollector{
+		db: db,
+		runningApps: prometheus.NewDesc(
+			"webmanager_project_running_apps",
+			"Number of running apps per project.",
+			[]string{"project"}, nil,
+		),
+		serverOnline: prometheus.NewDesc(
+			"webmanager_server_online",
+			"Whether the server was online at its last status check (1) or not (0).",
+			[]string{"server", "address"}, nil,
+		),
+	}
+}
+
+func (c *dbCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.runningApps
+	ch <- c.serverOnline
+}
+
+func (c *dbCollector) Collect(ch chan<- prometheus.Metric) {
+	var projects []struct {
+		Name    string
+		Running int
+	}
+	err := c.db.Raw(`
+		SELECT p.name, count(a.id) AS running
+		FROM projects p
+		LEFT JOIN apps a ON a.project_id = p.id AND a.status = 'running' AND a.deleted_at IS NULL
+		WHERE p.deleted_at IS NULL
+		GROUP BY p.name`).Scan(&projects).Error
+	if err != nil {
+		log.Printf("Metrics: could not count running apps: %v", err)
+	}
+	for _, p := range projects {
+		ch <- prometheus.MustNewConstMetric(c.runningApps, prometheus.GaugeValue, float64(p.Running), p.Name)
+	}
+
+	var servers []struct {
+		Name    string
+		Address string
+		Status  string
+	}
+	err = c.db.Raw(`SELECT name, address, status FROM servers WHERE deleted_at IS NULL`).Scan(&servers).Error
+	if err != nil {
+		log.Printf("Metrics: could not load server status: %v", err)
+	}
+	for _, s := range servers {
+		online := 0.0
+		if s.Status == "online" {
+			online = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.serverOnline, prometheus.GaugeValue, online, s.Name, s.Address)
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "webmanager_http_requests_total",
+		Help: "HTTP requests handled, by Gin route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "webmanager_http_request_duration_seconds",
+		Help:    "HTTP request latency by Gin route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	SSHCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "webmanager_ssh_command_duration_seconds",
+		Help:    "Duration of SSH commands run on managed servers.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"server"})
+
+	SSHCommandFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "webmanager_ssh_command_failures_total",
+		Help: "SSH commands that failed to connect or exited non-zero.",
+	}, []string{"server"})
+
+	AutoStopExecutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "webmanager_auto_stop_executions_total",
+		Help: "Apps stopped by the auto-stop sweeper, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		SSHCommandDuration,
+		SSHCommandFailures,
+		AutoStopExecutions,
+	)
+}
+
+// RegisterJobQueueDepth reports the number of background jobs waiting for a
+// worker, as returned by depth at scrape time
+func RegisterJobQueueDepth(depth func() int) {
+	registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "webmanager_job_queue_depth",
+		Help: "Background jobs waiting for a worker.",
+	}, func() float64 { return float64(depth()) }))
+}
+
+var registerDB sync.Once
+
+// Handler serves the registry in the Prometheus text format. Gauges derived
+// from the database are read from db at scrape time.
+func Handler(db *gorm.DB) http.Handler {
+	registerDB.Do(func() {
+		registry.MustRegister(newDBCollector(db))
+	})
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -11,6 +11,11 @@ import (
 func JWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		// Browsers cannot set headers on EventSource and WebSocket requests,
+		// so streams may pass the token as ?access_token= instead
+		if authHeader == "" && isStreamRequest(c) && c.Query("access_token") != "" {
+			authHeader = "Bearer " + c.Query("access_token")
+		}
 		if !strings.HasPrefix(authHeader, "Bearer ") {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid token"})
 			c.Abort()
@@ -58,3 +63,9 @@ func Admin() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// isStreamRequest reports whether a request opens a server-sent event stream or a WebSocket
+func isStreamRequest(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), "text/event-stream") ||
+		strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import "testing"
+
+func TestRedactAccessToken(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/api/apps", "/api/apps"},
+		{"/api/apps?limit=5", "/api/apps?limit=5"},
+		{"/api/apps/1/logs?access_token=abc.def.ghi", "/api/apps/1/logs?access_token=REDACTED"},
+		{"/api/apps/1/logs?follow=1&access_token=abc&tail=10", "/api/apps/1/logs?access_token=REDACTED&follow=1&tail=10"},
+		{"/api/apps/1/logs?access_token=a;b", "/api/apps/1/logs?[unparsable query]"},
+	}
+	for _, tt := range tests {
+		if got := redactAccessToken(tt.path); got != tt.want {
+			t.Errorf("redactAccessToken(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
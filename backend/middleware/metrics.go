@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records request counts and latencies per Gin route
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsToken protects the metrics endpoint with METRICS_TOKEN when it is set
+func MetricsToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := os.Getenv("METRICS_TOKEN")
+		if token == "" {
+			c.Next()
+			return
+		}
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
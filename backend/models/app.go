@@ -19,6 +19,26 @@ type App struct {
     Status          string         `gorm:"not null;default:'stopped'" json:"status"`
     StartedAt       *time.Time     `json:"startedAt"`
     TimerEndsAt     *int64         `gorm:"column:timer_ends_at" json:"timerEndsAt"`
+    ExpiryWarned    bool           `gorm:"not null;default:false" json:"-"` // "Stops soon" email already sent for this run
+    StartedByID     *string        `gorm:"type:uuid" json:"startedById"` // Nil when stopped or started by WebManager itself
+    StartedByUser   *User          `gorm:"foreignKey:StartedByID" json:"-"`
+    StartedBy       string         `json:"startedBy"` // Username, or e.g. "scheduler"
+    LastAction      string         `json:"lastAction"` // Audit action of the last start/stop, e.g. "auto_stop_app"
+    LastActionBy    string         `json:"lastActionBy"`
+    LastActionAt    *time.Time     `json:"lastActionAt"`
+    HealthStatus    string         `json:"healthStatus"` // "", "starting", "healthy", "unhealthy", see AppHealthCheck
+    HealthMessage   string         `json:"healthMessage"`
+    HealthCheckedAt *time.Time     `json:"healthCheckedAt"`
+    IdleStopMinutes int            `gorm:"not null;default:0" json:"idleStopMinutes"` // Stop after this long without traffic or CPU use, 0 to disable
+    LastActiveAt    *time.Time     `json:"lastActiveAt"` // Last observed container or proxy activity
+    IdleWarnedAt    *time.Time     `json:"idleWarnedAt"` // Set while an idle stop is pending
+    LastHeartbeatAt *time.Time     `json:"lastHeartbeatAt"` // Last "still in use" signal, see POST /apps/:id/heartbeat
+    LastHeartbeatBy string         `json:"lastHeartbeatBy"`
+    WakeOnRequest   bool           `gorm:"not null;default:false" json:"wakeOnRequest"` // Start on the first request through the built-in proxy
+    ProxyPort       int            `json:"proxyPort"` // Host port the reverse proxy routes Domain to, 0 for the first published TCP port
+    PublishedPorts  []PublishedPort `gorm:"serializer:json;type:text" json:"publishedPorts"` // Host ports of the last start
+    PinnedDeploymentID *string     `gorm:"type:uuid" json:"pinnedDeploymentId"` // Set after a rollback: images are pinned to this deployment
+    DependsOn       []string       `gorm:"-" json:"dependsOn"` // IDs of apps started before this one, see AppDependency
     CreatedAt       time.Time      `json:"createdAt"`
     UpdatedAt       time.Time      `json:"updatedAt"`
     DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// AppDependency records that AppID needs DependsOnID running before it starts
+type AppDependency struct {
+	AppID       string    `gorm:"type:uuid;primaryKey" json:"appId"`
+	DependsOnID string    `gorm:"type:uuid;primaryKey;index" json:"dependsOnId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// AppEnvVar is an environment variable written to the .env file of an app.
+// Secret values are stored encrypted and never returned by the API.
+type AppEnvVar struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AppID     string    `gorm:"type:uuid;not null;uniqueIndex:idx_app_env_vars_app_key" json:"appId"`
+	Key       string    `gorm:"not null;uniqueIndex:idx_app_env_vars_app_key" json:"key"`
+	Value     string    `gorm:"not null" json:"value"`
+	Secret    bool      `gorm:"not null" json:"secret"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AppEnvChange is one entry in the change history of an app's environment.
+// Values of secret variables are not kept.
+type AppEnvChange struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AppID     string    `gorm:"type:uuid;not null;index" json:"appId"`
+	Key       string    `gorm:"not null" json:"key"`
+	Action    string    `gorm:"not null" json:"action"` // "create", "update", "delete"
+	OldValue  string    `json:"oldValue"`
+	NewValue  string    `json:"newValue"`
+	Secret    bool      `gorm:"not null" json:"secret"`
+	UserID    *string   `gorm:"type:uuid" json:"userId"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// AppHealthCheck defines how to verify over HTTP that a started app works
+type AppHealthCheck struct {
+	ID              string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AppID           string    `gorm:"type:uuid;not null;uniqueIndex" json:"appId"`
+	URL             string    `gorm:"not null" json:"url"` // Full URL, or a path on the app's AppURL/Domain
+	ExpectedStatus  int       `gorm:"not null;default:200" json:"expectedStatus"`
+	BodyMatch       string    `json:"bodyMatch"` // Substring the response body must contain
+	TimeoutSeconds  int       `gorm:"not null;default:120" json:"timeoutSeconds"` // Give up after this long
+	IntervalSeconds int       `gorm:"not null;default:5" json:"intervalSeconds"`
+	SkipTLSVerify   bool      `gorm:"not null;default:false" json:"skipTlsVerify"`
+	Enabled         bool      `gorm:"not null" json:"enabled"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AppSchedule starts or stops an app on a cron schedule
+type AppSchedule struct {
+	ID              string         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AppID           string         `gorm:"type:uuid;not null;index" json:"appId"`
+	CronExpr        string         `gorm:"not null" json:"cron"` // Standard 5-field expression or descriptor such as @daily
+	Timezone        string         `gorm:"not null" json:"timezone"`
+	Action          string         `gorm:"not null" json:"action"`                    // "start", "stop"
+	AutoStopMinutes int            `gorm:"not null;default:0" json:"autoStopMinutes"` // Start only; 0 runs until stopped
+	Enabled         bool           `gorm:"not null" json:"enabled"`
+	NextRunAt       *time.Time     `gorm:"index" json:"nextRunAt"`
+	LastRunAt       *time.Time     `json:"lastRunAt"`
+	LastResult      string         `json:"lastResult"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+}
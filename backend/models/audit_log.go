@@ -1,14 +1,28 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// AuditArchiveKey marks a gorm session allowed to delete audit log entries,
+// set by the retention job once the entries are archived
+const AuditArchiveKey = "audit_log:archived"
+
+// ErrAuditLogImmutable is returned when audit log entries are deleted outside the retention job
+var ErrAuditLogImmutable = errors.New("audit log entries can only be removed by the archival retention job")
+
+// AuditLog entries are never soft deleted: they stay until archived and
+// hard deleted by the retention policy. Entries form a hash chain: Hash covers
+// the entry's content and PrevHash, the Hash of the entry before it.
 type AuditLog struct {
 	ID          string         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	UserID      string         `gorm:"type:uuid;not null" json:"userId"`
+	Sequence    int64          `gorm:"uniqueIndex" json:"sequence"` // Position in the hash chain, starting at 1
+	PrevHash    string         `json:"prevHash"`
+	Hash        string         `json:"hash"`
+	UserID      *string        `gorm:"type:uuid" json:"userId"` // Nil for actions WebManager performs itself
 	Username    string         `gorm:"not null" json:"username"`
 	Action      string         `gorm:"not null" json:"action"` // "start_app", "stop_app", "create_app", etc.
 	ResourceID  string         `gorm:"type:uuid" json:"resourceId"` // App ID, Server ID, etc.
@@ -17,7 +31,14 @@ type AuditLog struct {
 	Details     string         `json:"details"` // Additional details like duration, etc.
 	IPAddress   string         `json:"ipAddress"`
 	UserAgent   string         `json:"userAgent"`
-	CreatedAt   time.Time      `json:"createdAt"`
+	CreatedAt   time.Time      `gorm:"index" json:"createdAt"`
 	UpdatedAt   time.Time      `json:"updatedAt"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-}
\ No newline at end of file
+}
+
+// BeforeDelete refuses deletes that do not come from the retention job
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	if archived, ok := tx.Get(AuditArchiveKey); ok && archived == true {
+		return nil
+	}
+	return ErrAuditLogImmutable
+}
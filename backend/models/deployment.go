@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// ServiceImage is the image a compose service's container runs
+type ServiceImage struct {
+	Service string `json:"service"`
+	Image   string `json:"image"`   // Reference from the compose file, e.g. "nginx:1.27"
+	ImageID string `json:"imageId"` // Local image ID, "sha256:..."
+	Digest  string `json:"digest"`  // Repository digest, "nginx@sha256:...", empty for local builds
+}
+
+// Deployment records the images an app ran after a start, redeploy or rollback
+type Deployment struct {
+	ID             string         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AppID          string         `gorm:"type:uuid;not null;index" json:"appId"`
+	Action         string         `gorm:"not null" json:"action"` // "start", "redeploy", "rollback"
+	Images         []ServiceImage `gorm:"serializer:json;type:text" json:"images"`
+	RolledBackToID *string        `gorm:"type:uuid" json:"rolledBackToId"` // Deployment whose images a rollback restored
+	UserID         *string        `gorm:"type:uuid" json:"userId"`
+	Username       string         `json:"username"`
+	CreatedAt      time.Time      `gorm:"index" json:"createdAt"`
+}
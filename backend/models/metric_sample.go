@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MetricSample is one point of a server or app time series. Raw samples have
+// Resolution 0; downsampled rows aggregate Count samples into a bucket of
+// Resolution seconds starting at SampledAt.
+type MetricSample struct {
+	ID           uint64    `gorm:"primaryKey" json:"-"`
+	ResourceType string    `gorm:"not null;index:idx_metric_series,priority:1" json:"resourceType"` // "server", "app"
+	ResourceID   string    `gorm:"type:uuid;not null;index:idx_metric_series,priority:2" json:"resourceId"`
+	Metric       string    `gorm:"not null;index:idx_metric_series,priority:3" json:"metric"`
+	Resolution   int       `gorm:"not null;default:0;index:idx_metric_series,priority:4" json:"resolution"`
+	SampledAt    time.Time `gorm:"not null;index:idx_metric_series,priority:5" json:"sampledAt"`
+	Value        float64   `gorm:"not null" json:"value"` // Average over the bucket
+	Min          float64   `gorm:"not null" json:"min"`
+	Max          float64   `gorm:"not null" json:"max"`
+	Count        int       `gorm:"not null;default:1" json:"count"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationPreference holds a user's email opt-ins; a missing row means no emails
+type NotificationPreference struct {
+	UserID        string    `gorm:"type:uuid;primaryKey" json:"userId"`
+	AppExpiring   bool      `gorm:"not null" json:"appExpiring"`   // App auto-stops soon
+	StartFailed   bool      `gorm:"not null" json:"startFailed"`   // Starting an app failed
+	ServerOffline bool      `gorm:"not null" json:"serverOffline"` // A server went offline
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// ProjectMember grants a user a role within a project
+type ProjectMember struct {
+	ProjectID string    `gorm:"type:uuid;primaryKey" json:"projectId"`
+	UserID    string    `gorm:"type:uuid;primaryKey" json:"userId"`
+	Role      string    `gorm:"not null" json:"role"` // "admin", "member"
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// PublishedPort is a host port published by a compose service
+type PublishedPort struct {
+	Service  string `json:"service"`
+	HostIP   string `json:"hostIp"` // Empty for all interfaces
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"` // "tcp", "udp"
+}
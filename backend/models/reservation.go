@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reservation books an app for one user during [StartsAt, EndsAt)
+type Reservation struct {
+	ID          string         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AppID       string         `gorm:"type:uuid;not null;index" json:"appId"`
+	UserID      string         `gorm:"type:uuid;not null" json:"userId"`
+	Username    string         `gorm:"not null" json:"username"`
+	StartsAt    time.Time      `gorm:"not null;index" json:"startsAt"`
+	EndsAt      time.Time      `gorm:"not null;index" json:"endsAt"`
+	Reason      string         `gorm:"not null" json:"reason"`
+	AutoStart   bool           `gorm:"not null" json:"autoStart"` // Start the app when the window begins
+	AutoStop    bool           `gorm:"not null" json:"autoStop"`  // Stop the app when the window ends
+	AutoStarted bool           `gorm:"not null" json:"-"`
+	AutoStopped bool           `gorm:"not null" json:"-"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
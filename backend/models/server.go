@@ -16,7 +16,27 @@ type Server struct {
     Status           string         `gorm:"not null;default:'offline'" json:"status"`
     RunningAppsCount int            `gorm:"-" json:"runningAppsCount"` // Computed field
     LastChecked      *int64         `json:"lastChecked"`
+    Metrics          HostMetrics    `gorm:"embedded;embeddedPrefix:metrics_" json:"metrics"`
+    ProxyType        string         `json:"proxyType"` // "nginx" or "traefik" to generate reverse-proxy routes for its apps, "" for none
+    ProxyConfigPath  string         `json:"proxyConfigPath"` // Generated config file, defaults per proxy type
+    ProxyReloadCmd   string         `json:"proxyReloadCmd"` // Validates and reloads the proxy, defaults to "nginx -t && nginx -s reload" for nginx
+    ProxyUpstreamHost string        `json:"proxyUpstreamHost"` // Host the proxy reaches published ports on, defaults to 127.0.0.1
     CreatedAt        time.Time      `json:"createdAt"`
     UpdatedAt        time.Time      `json:"updatedAt"`
     DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// HostMetrics is the last snapshot of host resources collected over SSH
+type HostMetrics struct {
+    CPUUsage      float64 `json:"cpuUsage"` // Percent across all cores
+    LoadAvg1      float64 `json:"loadAvg1"`
+    LoadAvg5      float64 `json:"loadAvg5"`
+    LoadAvg15     float64 `json:"loadAvg15"`
+    MemTotal      int64   `json:"memTotal"` // Bytes
+    MemAvailable  int64   `json:"memAvailable"`
+    DockerRootDir string  `json:"dockerRootDir"`
+    DiskTotal     int64   `json:"diskTotal"` // Bytes on the docker root filesystem
+    DiskFree      int64   `json:"diskFree"`
+    Uptime        int64   `json:"uptime"` // Seconds
+    CollectedAt   *int64  `json:"collectedAt"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// SessionRecording is an asciicast v2 transcript of a terminal session,
+// stored on local disk and linked to the audit entry of the session start
+type SessionRecording struct {
+	ID           string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AuditLogID   string     `gorm:"type:uuid;not null;index" json:"auditLogId"`
+	ResourceType string     `gorm:"not null" json:"resourceType"` // "app", "server"
+	ResourceID   string     `gorm:"type:uuid;index" json:"resourceId"`
+	ResourceName string     `json:"resourceName"`
+	UserID       *string    `gorm:"type:uuid;index" json:"userId"`
+	Username     string     `gorm:"not null" json:"username"`
+	Title        string     `json:"title"`
+	Path         string     `gorm:"not null" json:"-"`
+	Size         int64      `gorm:"not null;default:0" json:"size"`
+	Truncated    bool       `gorm:"not null;default:false" json:"truncated"` // Output beyond RECORDING_MAX_MB was not recorded
+	StartedAt    time.Time  `gorm:"not null;index" json:"startedAt"`
+	EndedAt      *time.Time `json:"endedAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Webhook struct {
+	ID        string         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Name      string         `gorm:"not null" json:"name"`
+	URL       string         `gorm:"not null" json:"url"`
+	Events    string         `gorm:"not null;default:'*'" json:"events"` // Comma-separated event names, "*" for all
+	Secret    string         `json:"-"`                                  // HMAC key for X-WebManager-Signature
+	Active    bool           `gorm:"not null" json:"active"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID           string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	WebhookID    string    `gorm:"type:uuid;not null;index" json:"webhookId"`
+	EventID      string    `gorm:"not null;index" json:"eventId"`
+	Event        string    `gorm:"not null" json:"event"`
+	Payload      string    `gorm:"type:text" json:"payload"`
+	Attempt      int       `gorm:"not null" json:"attempt"`
+	StatusCode   int       `json:"statusCode"` // 0 when no response was received
+	ResponseBody string    `gorm:"type:text" json:"responseBody"`
+	Error        string    `json:"error"`
+	Success      bool      `gorm:"not null" json:"success"`
+	DurationMs   int64     `json:"durationMs"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/services"
+)
+
+// wakeState tracks a start triggered by a request to a stopped app
+type wakeState struct {
+	err error
+	at  time.Time
+}
+
+// wakes holds the wake state per app ID; a failed wake is retried after a minute
+var wakes sync.Map
+
+var waitingPage = template.Must(template.New("waiting").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8">{{if not .Error}}<meta http-equiv="refresh" content="3">{{end}}
+<title>Starting {{.Name}}</title>
+<style>body{font-family:sans-serif;text-align:center;padding-top:15vh;color:#333}</style></head>
+<body>
+{{if .Error}}<h1>{{.Name}} could not be started</h1>
+<p>{{.Error}}</p>
+<p>Reload the page in a minute to try again.</p>
+{{else}}<h1>Starting {{.Name}}&hellip;</h1>
+<p>This environment was sleeping. The page reloads by itself once it is ready.</p>
+{{end}}</body>
+</html>
+`))
+
+// Start serves the built-in scale-to-zero proxy on PROXY_LISTEN_ADDR, if set.
+// Requests are routed by Host to the app with that Domain: running apps are
+// proxied, stopped apps with WakeOnRequest are started behind a waiting page.
+func Start(db *gorm.DB) {
+	addr := os.Getenv("PROXY_LISTEN_ADDR")
+	if addr == "" {
+		return
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           &handler{db: db},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("App proxy listening on %s", addr)
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("App proxy stopped: %v", err)
+		}
+	}()
+}
+
+type handler struct {
+	db *gorm.DB
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	var app models.App
+	if err := h.db.First(&app, "domain = ?", host).Error; err != nil {
+		http.Error(w, "Unknown environment "+host, http.StatusNotFound)
+		return
+	}
+	server, err := services.GetServerByID(h.db, app.ServerID)
+	if err != nil {
+		http.Error(w, "Server not found for "+app.Name, http.StatusBadGateway)
+		return
+	}
+
+	switch {
+	case app.Status == "running" && app.HealthStatus != "starting":
+		services.RecordAppActivity(h.db, app)
+		h.proxy(w, r, server, app)
+	case app.Status == "running":
+		writeWaitingPage(w, r, app, nil)
+	case app.WakeOnRequest:
+		writeWaitingPage(w, r, app, h.wake(server, app))
+	default:
+		w.Header().Set("Retry-After", "60")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(services.RenderSleepingPage(app)))
+	}
+}
+
+// proxy forwards a request to the app's published port on its server
+func (h *handler) proxy(w http.ResponseWriter, r *http.Request, server models.Server, app models.App) {
+	port := services.ProxyPort(app)
+	if port == 0 {
+		http.Error(w, app.Name+" publishes no port to proxy to", http.StatusBadGateway)
+		return
+	}
+	upstreamHost := server.ProxyUpstreamHost
+	if upstreamHost == "" || upstreamHost == "127.0.0.1" {
+		// The proxy runs next to WebManager, not on the app's server
+		upstreamHost = server.Address
+	}
+	target := &url.URL{Scheme: "http", Host: net.JoinHostPort(upstreamHost, fmt.Sprint(port))}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out.Host = pr.In.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("App proxy: %s: %v", app.Name, err)
+			http.Error(w, app.Name+" is not responding", http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// wake starts a stopped app once, returning the error of a recent failed attempt
+func (h *handler) wake(server models.Server, app models.App) error {
+	// Claim the wake atomically so concurrent first requests queue one start
+	fresh := wakeState{at: time.Now()}
+	if previous, loaded := wakes.LoadOrStore(app.ID, fresh); loaded {
+		state := previous.(wakeState)
+		if state.err == nil || time.Since(state.at) < time.Minute {
+			return state.err
+		}
+		// Retry a failed wake, unless another request just did
+		if !wakes.CompareAndSwap(app.ID, previous, fresh) {
+			return nil
+		}
+	}
+
+	err := services.EnqueueJob("wake "+app.Name, func() {
+		var current models.App
+		if err := h.db.First(&current, "id = ?", app.ID).Error; err != nil {
+			wakes.Delete(app.ID)
+			return
+		}
+		if current.Status == "running" {
+			wakes.Delete(app.ID)
+			return
+		}
+		_, err := services.StartApp(h.db, server, &current, services.SystemActor("wake-on-request"), "wake_start_app", current.AutoStopTimeout)
+		if err != nil {
+			log.Printf("App proxy: could not wake %s: %v", app.Name, err)
+			wakes.Store(app.ID, wakeState{err: errors.New("start failed"), at: time.Now()})
+			return
+		}
+		wakes.Delete(app.ID)
+	})
+	if err != nil {
+		wakes.Delete(app.ID)
+		return err
+	}
+	return nil
+}
+
+func writeWaitingPage(w http.ResponseWriter, r *http.Request, app models.App, wakeErr error) {
+	w.Header().Set("Retry-After", "5")
+	w.Header().Set("Cache-Control", "no-store")
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Error(w, app.Name+" is starting, retry shortly", http.StatusServiceUnavailable)
+		return
+	}
+
+	data := map[string]string{"Name": app.Name}
+	if wakeErr != nil {
+		data["Error"] = wakeErr.Error()
+	}
+	var buf bytes.Buffer
+	waitingPage.Execute(&buf, data)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write(buf.Bytes())
+}
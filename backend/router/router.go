@@ -2,6 +2,7 @@ package router
 
 import (
     "backend/controllers"
+    "backend/metrics"
     "backend/middleware"
     "github.com/gin-contrib/cors"
     "github.com/gin-gonic/gin"
@@ -9,7 +10,10 @@ import (
 )
 
 func SetupRouter(db *gorm.DB) *gin.Engine {
-    r := gin.Default()
+    // gin.Default() without its logger, which would log stream access tokens
+    r := gin.New()
+    r.Use(middleware.Logger(), gin.Recovery())
+    r.Use(middleware.Metrics())
     
     // Add CORS middleware
     r.Use(cors.New(cors.Config{
@@ -20,6 +24,12 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
         AllowCredentials: true,
     }))
 
+    // Prometheus metrics (protected by METRICS_TOKEN when set)
+    r.GET("/metrics", middleware.MetricsToken(), gin.WrapH(metrics.Handler(db)))
+
+    // "Environment sleeping" page that Traefik routes stopped apps to
+    r.GET("/sleeping", controllers.SleepingPage(db))
+
     // Public auth routes (login - no JWT required)
     r.POST("/api/auth/login", controllers.Login(db))
     r.POST("/api/auth/register", controllers.Register(db))
@@ -32,13 +42,33 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 
     // User routes (can see servers, projects, apps + start/stop apps)
     auth.GET("/servers", controllers.ListServers(db))
+    auth.GET("/servers/:id", controllers.GetServer(db))
     auth.POST("/servers/refresh", controllers.RefreshAllServers(db))
     auth.POST("/servers/:id/test", controllers.TestServerConnection(db))
+    auth.GET("/servers/:id/metrics", controllers.GetServerMetrics(db))
     auth.GET("/projects", controllers.ListProjects(db))
     auth.GET("/apps", controllers.ListApps(db))
+    auth.GET("/apps/:id/metrics", controllers.GetAppMetrics(db))
+    auth.GET("/apps/:id/logs", controllers.GetAppLogs(db))
+    auth.GET("/apps/:id/health-check", controllers.GetAppHealthCheck(db))
+    auth.POST("/apps/:id/health-check/run", controllers.RunAppHealthCheck(db))
+    auth.GET("/apps/:id/terminal", controllers.AppTerminal(db)) // WebSocket, admins and project admins
     auth.POST("/apps/:id/start", controllers.StartApp(db))
     auth.POST("/apps/:id/stop", controllers.StopApp(db))
+    auth.POST("/apps/:id/heartbeat", controllers.AppHeartbeat(db))
+    auth.POST("/apps/:id/redeploy", controllers.RedeployApp(db))
+    auth.GET("/apps/:id/deployments", controllers.ListAppDeployments(db))
+    auth.POST("/apps/:id/rollback", controllers.RollbackApp(db))
+    auth.GET("/apps/:id/schedules", controllers.ListAppSchedules(db))
+    auth.GET("/schedules/:id/preview", controllers.PreviewSchedule(db))
+    auth.GET("/apps/:id/reservations", controllers.ListAppReservations(db))
+    auth.POST("/apps/:id/reservations", controllers.CreateReservation(db))
+    auth.DELETE("/reservations/:id", controllers.DeleteReservation(db))
     
+    // Email notification opt-ins of the current user
+    auth.GET("/notifications/preferences", controllers.GetNotificationPreferences(db))
+    auth.PUT("/notifications/preferences", controllers.UpdateNotificationPreferences(db))
+
     // User can view their own audit logs
     auth.GET("/audit-logs", controllers.GetAuditLogs(db))
 
@@ -49,15 +79,58 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
     admin.POST("/servers", controllers.CreateServer(db))
     admin.PUT("/servers/:id", controllers.UpdateServer(db))
     admin.DELETE("/servers/:id", controllers.DeleteServer(db))
+    admin.GET("/servers/:id/terminal", controllers.ServerTerminal(db)) // WebSocket
+    admin.GET("/servers/:id/proxy-config", controllers.GetProxyConfig(db))
+    admin.POST("/servers/:id/proxy-config/sync", controllers.SyncProxyConfig(db))
 
     admin.POST("/projects", controllers.CreateProject(db))
     admin.PUT("/projects/:id", controllers.UpdateProject(db))
     admin.DELETE("/projects/:id", controllers.DeleteProject(db))
+    admin.GET("/projects/:id/members", controllers.ListProjectMembers(db))
+    admin.PUT("/projects/:id/members/:userId", controllers.SetProjectMember(db))
+    admin.DELETE("/projects/:id/members/:userId", controllers.RemoveProjectMember(db))
 
     admin.POST("/apps", controllers.CreateApp(db))
     admin.PUT("/apps/:id", controllers.UpdateApp(db))
     admin.DELETE("/apps/:id", controllers.DeleteApp(db))
 
+    // Managed .env variables, written to the server on every start
+    admin.GET("/apps/:id/env", controllers.ListAppEnv(db))
+    admin.PUT("/apps/:id/env/:key", controllers.SetAppEnvVar(db))
+    admin.DELETE("/apps/:id/env/:key", controllers.DeleteAppEnvVar(db))
+    admin.GET("/apps/:id/env/history", controllers.ListAppEnvHistory(db))
+    admin.GET("/apps/:id/env/diff", controllers.GetAppEnvDiff(db))
+
+    admin.PUT("/apps/:id/health-check", controllers.SetAppHealthCheck(db))
+    admin.DELETE("/apps/:id/health-check", controllers.DeleteAppHealthCheck(db))
+    admin.PUT("/apps/:id/idle-policy", controllers.SetAppIdlePolicy(db))
+
+    // Compose files on the app's server, edited over SFTP
+    admin.GET("/apps/:id/compose", controllers.ListComposeFiles(db))
+    admin.PUT("/apps/:id/compose/:file", controllers.UpdateComposeFile(db))
+
+    admin.POST("/apps/:id/schedules", controllers.CreateAppSchedule(db))
+    admin.PUT("/schedules/:id", controllers.UpdateSchedule(db))
+    admin.DELETE("/schedules/:id", controllers.DeleteSchedule(db))
+
+    // Outgoing webhooks (admin only)
+    admin.GET("/webhooks", controllers.ListWebhooks(db))
+    admin.POST("/webhooks", controllers.CreateWebhook(db))
+    admin.PUT("/webhooks/:id", controllers.UpdateWebhook(db))
+    admin.DELETE("/webhooks/:id", controllers.DeleteWebhook(db))
+    admin.GET("/webhooks/:id/deliveries", controllers.ListWebhookDeliveries(db))
+    admin.POST("/webhooks/:id/test", controllers.TestWebhook(db))
+
+    admin.POST("/notifications/test", controllers.SendTestNotification(db))
+
+    // Terminal session recordings (admin only)
+    admin.GET("/recordings", controllers.ListRecordings(db))
+    admin.GET("/recordings/:id/download", controllers.DownloadRecording(db))
+    admin.GET("/audit-logs/export", controllers.ExportAuditLogs(db))
+    admin.GET("/audit-logs/archive", controllers.GetAuditArchiveStatus(db))
+    admin.GET("/audit-logs/verify", controllers.VerifyAuditLogs(db))
+    admin.GET("/audit-logs/:id/recording", controllers.GetAuditLogRecording(db))
+
     // User management (admin only)
     admin.GET("/users", controllers.ListUsers(db))
     admin.POST("/users", controllers.CreateUser(db))
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"backend/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// appActivity holds the time of the last proxied request per app ID
+var appActivity sync.Map
+
+// timerResets holds when each app's auto-stop timer was last reset by activity
+var timerResets sync.Map
+
+// RecordAppActivity notes a request to an app and pushes its auto-stop timer
+// back by the app's timeout, writing to the database at most once a minute
+func RecordAppActivity(db *gorm.DB, app models.App) {
+	now := time.Now()
+	appActivity.Store(app.ID, now)
+
+	if app.TimerEndsAt == nil || app.AutoStopTimeout <= 0 {
+		return
+	}
+	if last, ok := timerResets.Load(app.ID); ok && now.Sub(last.(time.Time)) < time.Minute {
+		return
+	}
+	timerResets.Store(app.ID, now)
+
+	timerEndsAt := now.Add(time.Duration(app.AutoStopTimeout) * time.Minute).Unix()
+	if timerEndsAt <= *app.TimerEndsAt {
+		return
+	}
+	db.Model(&models.App{}).Where("id = ? AND status = ?", app.ID, "running").
+		Updates(map[string]interface{}{"timer_ends_at": timerEndsAt, "expiry_warned": false})
+}
+
+// LastAppActivity returns when the built-in proxy last served a request to an app
+func LastAppActivity(appID string) (time.Time, bool) {
+	last, ok := appActivity.Load(appID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return last.(time.Time), true
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AppStats is the sum of `docker stats` readings over an app's containers
+type AppStats struct {
+	Containers  int
+	CPUUsage    float64 // Percent of one core, summed over containers
+	MemoryUsage int64   // Bytes
+	NetRxBytes  int64   // Cumulative since container start
+	NetTxBytes  int64
+}
+
+// CollectAppStats reads a single `docker stats` snapshot for the containers of a compose app
+func CollectAppStats(server models.Server, composePath string) (AppStats, error) {
+	cmd := fmt.Sprintf(`cd %s && ids=$(docker-compose ps -q) && if [ -n "$ids" ]; then docker stats --no-stream --format '{{.CPUPerc}}|{{.MemUsage}}|{{.NetIO}}' $ids; fi`, composePath)
+	output, err := SSHCommand(server, cmd)
+	if err != nil {
+		return AppStats{}, fmt.Errorf("failed to get app stats: %w", err)
+	}
+	return parseDockerStats(output)
+}
+
+func parseDockerStats(output string) (AppStats, error) {
+	var stats AppStats
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, "|")
+		if len(parts) != 3 {
+			return stats, fmt.Errorf("unexpected docker stats line: %q", line)
+		}
+		stats.Containers++
+
+		if cpu, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(parts[0]), "%"), 64); err == nil {
+			stats.CPUUsage += cpu
+		}
+		// "12.5MiB / 1.944GiB" -> usage / limit
+		if mem := strings.SplitN(parts[1], "/", 2); len(mem) == 2 {
+			stats.MemoryUsage += parseDockerSize(mem[0])
+		}
+		// "1.2kB / 648B" -> received / sent
+		if net := strings.SplitN(parts[2], "/", 2); len(net) == 2 {
+			stats.NetRxBytes += parseDockerSize(net[0])
+			stats.NetTxBytes += parseDockerSize(net[1])
+		}
+	}
+	return stats, nil
+}
+
+// parseDockerSize converts docker's human readable sizes ("1.5kB", "12MiB") to bytes
+func parseDockerSize(value string) int64 {
+	value = strings.TrimSpace(value)
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"B", 1},
+	}
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			number, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), 64)
+			if err != nil {
+				return 0
+			}
+			return int64(number * unit.multiplier)
+		}
+	}
+	return 0
+}
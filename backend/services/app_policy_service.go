@@ -0,0 +1,39 @@
+package services
+
+import (
+	"backend/models"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// IsProjectAdmin reports whether a user has the admin role in a project
+func IsProjectAdmin(db *gorm.DB, projectID, userID string) bool {
+	if projectID == "" || userID == "" {
+		return false
+	}
+	var count int64
+	db.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND user_id = ? AND role = ?", projectID, userID, "admin").
+		Count(&count)
+	return count > 0
+}
+
+// StopRestrictedToOwner reports whether STOP_POLICY limits stopping a running
+// app to its starter, project admins and global admins. The default policy
+// "anyone" lets every user stop any app.
+func StopRestrictedToOwner() bool {
+	return os.Getenv("STOP_POLICY") == "owner"
+}
+
+// CanStopApp applies the stop policy to a user stopping a running app
+func CanStopApp(db *gorm.DB, app models.App, actor Actor, isAdmin bool) bool {
+	if !StopRestrictedToOwner() || isAdmin || app.Status != "running" {
+		return true
+	}
+	// Apps started by WebManager itself (schedules) have no owner to protect
+	if app.StartedByID == nil || *app.StartedByID == actor.UserID {
+		return true
+	}
+	return IsProjectAdmin(db, app.ProjectID, actor.UserID)
+}
@@ -1,9 +1,12 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
+	"backend/metrics"
 	"backend/models"
 	"backend/utils"
 
@@ -18,31 +21,180 @@ func GetServerByID(db *gorm.DB, serverID string) (models.Server, error) {
 	return server, nil
 }
 
-func StartComposeApp(server models.Server, composePath string) (string, error) {
-	cmd := fmt.Sprintf("cd %s && docker-compose up -d", composePath)
+func StartComposeApp(server models.Server, app models.App) (string, error) {
+	cmd := fmt.Sprintf("cd %s && docker-compose%s up -d", app.ComposePath, composeFileArgs(app))
 	log.Printf("Executing command on %s: %s", server.Address, cmd)
 
-	out, err := utils.RunSSHCommand(server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey, cmd)
+	out, err := SSHCommand(server, cmd)
 	if err != nil {
 		log.Printf("Command failed on %s: %v", server.Address, err)
-		return "", fmt.Errorf("command failed: %w", err)
+		return "", err
 	}
 	return out, nil
 }
 
 func StopComposeApp(server models.Server, composePath string) (string, error) {
 	cmd := fmt.Sprintf("cd %s && docker-compose down", composePath)
-	out, err := utils.RunSSHCommand(server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey, cmd)
+	return SSHCommand(server, cmd)
+}
+
+func SSHCommand(server models.Server, command string) (string, error) {
+	out, err := runSSH(server, command)
 	if err != nil {
 		return "", fmt.Errorf("command failed: %w", err)
 	}
 	return out, nil
 }
 
-func SSHCommand(server models.Server, command string) (string, error) {
+// runSSH runs a command on a server and records its duration and failures
+func runSSH(server models.Server, command string) (string, error) {
+	start := time.Now()
 	out, err := utils.RunSSHCommand(server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey, command)
+	recordSSHCommand(server, start, err)
+	return out, err
+}
+
+// runSSHWithInput runs a command like runSSH, feeding input to its stdin
+func runSSHWithInput(server models.Server, command, input string) (string, error) {
+	start := time.Now()
+	out, err := utils.RunSSHCommandWithInput(server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey, command, input)
+	recordSSHCommand(server, start, err)
+	return out, err
+}
+
+func recordSSHCommand(server models.Server, start time.Time, err error) {
+	metrics.SSHCommandDuration.WithLabelValues(server.Name).Observe(time.Since(start).Seconds())
 	if err != nil {
-		return "", fmt.Errorf("command failed: %w", err)
+		metrics.SSHCommandFailures.WithLabelValues(server.Name).Inc()
+	}
+}
+
+// StartApp starts the dependencies of an app, then brings its compose stack up,
+// marks it running with an auto-stop timer of timeoutMinutes (0 for manual
+// stop) and audits the action
+func StartApp(db *gorm.DB, server models.Server, app *models.App, actor Actor, action string, timeoutMinutes int) (string, error) {
+	if err := startDependencies(db, app, actor, timeoutMinutes); err != nil {
+		NotifyStartFailed(db, *app, actor.UserID, err)
+		return "", err
+	}
+	out, err := startApp(db, server, app, actor, action, timeoutMinutes)
+	if err != nil {
+		return "", err
+	}
+	beginHealthCheck(db, app, actor)
+	return out, nil
+}
+
+func startApp(db *gorm.DB, server models.Server, app *models.App, actor Actor, action string, timeoutMinutes int) (string, error) {
+	if err := UploadEnvFile(db, server, *app); err != nil {
+		NotifyStartFailed(db, *app, actor.UserID, err)
+		return "", err
+	}
+	ports, err := CheckPortConflicts(db, server, *app)
+	if errors.Is(err, ErrPortConflict) {
+		NotifyStartFailed(db, *app, actor.UserID, err)
+		return "", err
+	}
+	if err != nil {
+		// docker-compose reports broken configurations itself on `up`
+		log.Printf("Port check of app %s skipped: %v", app.Name, err)
+	}
+	out, err := StartComposeApp(server, *app)
+	if err != nil {
+		NotifyStartFailed(db, *app, actor.UserID, err)
+		return "", err
 	}
+
+	// Restarting a running app leaves it owned by whoever started it
+	restarted := app.Status == "running"
+	app.Status = "running"
+	now := time.Now()
+	app.StartedAt = &now
+	if timeoutMinutes > 0 {
+		app.AutoStopTimeout = timeoutMinutes
+	} else {
+		app.AutoStopTimeout = 0 // Manual stop
+	}
+
+	// The auto-stop sweeper stops the app once the timer ends
+	app.TimerEndsAt = nil
+	app.ExpiryWarned = false
+	if app.AutoStopTimeout > 0 {
+		timerEndsAt := now.Add(time.Duration(app.AutoStopTimeout) * time.Minute).Unix()
+		app.TimerEndsAt = &timerEndsAt
+	}
+
+	if !restarted {
+		app.StartedByID = nil
+		if actor.UserID != "" {
+			app.StartedByID = &actor.UserID
+		}
+		app.StartedBy = actor.Username
+	}
+	app.PublishedPorts = ports
+	app.LastActiveAt = &now
+	app.IdleWarnedAt = nil
+	setLastAction(app, action, actor, now)
+
+	db.Save(app)
+	recordCurrentDeployment(db, server, *app, "start", actor)
+	QueueProxySync(db, server)
+
+	// Log the action and notify webhooks
+	duration := time.Duration(app.AutoStopTimeout) * time.Minute
+	LogAppActionAs(db, actor, action, *app, &duration)
+	EmitAppEvent(db, EventAppStarted, *app, actor)
+
+	return out, nil
+}
+
+// StopApp brings an app's compose stack down, marks it stopped and audits the
+// action, including reason when one was given
+func StopApp(db *gorm.DB, server models.Server, app *models.App, actor Actor, action, reason string) (string, error) {
+	out, err := StopComposeApp(server, app.ComposePath)
+	if err != nil {
+		return "", err
+	}
+
+	// Calculate duration if app was running
+	var duration *time.Duration
+	if app.StartedAt != nil {
+		d := time.Since(*app.StartedAt)
+		duration = &d
+	}
+
+	app.Status = "stopped"
+	app.StartedAt = nil
+	app.TimerEndsAt = nil
+	app.ExpiryWarned = false
+	app.StartedByID = nil
+	app.StartedBy = ""
+	app.HealthStatus = ""
+	app.HealthMessage = ""
+	app.IdleWarnedAt = nil
+	app.LastHeartbeatAt = nil
+	app.LastHeartbeatBy = ""
+	setLastAction(app, action, actor, time.Now())
+	db.Save(app)
+	QueueProxySync(db, server)
+
+	// Log the action and notify webhooks
+	details := AppActionDetails(*app, duration)
+	if reason != "" {
+		details += ", Reason: " + reason
+	}
+	LogActionAs(db, actor, action, "app", app.ID, app.Name, details)
+	event := EventAppStopped
+	if action == "auto_stop_app" || action == "idle_stop_app" {
+		event = EventAppAutoStopped
+	}
+	EmitAppEvent(db, event, *app, actor)
+
 	return out, nil
-}
\ No newline at end of file
+}
+
+func setLastAction(app *models.App, action string, actor Actor, at time.Time) {
+	app.LastAction = action
+	app.LastActionBy = actor.Username
+	app.LastActionAt = &at
+}
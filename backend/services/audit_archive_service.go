@@ -0,0 +1,215 @@
+package services
+
+import (
+	"backend/models"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditArchiveDir is where archived audit log entries are written (AUDIT_ARCHIVE_DIR)
+func AuditArchiveDir() string {
+	if dir := os.Getenv("AUDIT_ARCHIVE_DIR"); dir != "" {
+		return dir
+	}
+	return "audit-archive"
+}
+
+// auditRetention returns how long audit log entries stay in the database (AUDIT_RETENTION_DAYS)
+func auditRetention() time.Duration {
+	return time.Duration(envInt("AUDIT_RETENTION_DAYS", 365)) * 24 * time.Hour
+}
+
+// AuditArchiveFile is one compressed NDJSON archive on disk
+type AuditArchiveFile struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// AuditArchiveStatus reports the retention policy and the outcome of the last archival run
+type AuditArchiveStatus struct {
+	RetentionDays   int                `json:"retentionDays"`
+	Directory       string             `json:"directory"`
+	LastRunAt       *time.Time         `json:"lastRunAt"`
+	LastArchived    int                `json:"lastArchived"` // Entries archived by the last run
+	LastError       string             `json:"lastError"`
+	TotalArchived   int                `json:"totalArchived"` // Entries archived since WebManager started
+	OldestEntryAt   *time.Time         `json:"oldestEntryAt"` // Oldest entry still in the database
+	DatabaseEntries int64              `json:"databaseEntries"`
+	Archives        []AuditArchiveFile `json:"archives"`
+}
+
+var (
+	auditArchiveMu    sync.Mutex // Serializes archival runs and guards auditArchiveState
+	auditArchiveState AuditArchiveStatus
+)
+
+// StartAuditArchiver applies the audit log retention policy every hour
+func StartAuditArchiver(db *gorm.DB) {
+	log.Printf("Audit log entries older than %s are archived to %s", auditRetention(), AuditArchiveDir())
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if _, err := ArchiveAuditLogs(db, time.Now()); err != nil {
+				log.Printf("Audit log archival failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ArchiveAuditLogs moves entries older than the retention period into gzip
+// compressed NDJSON files, one per batch of AUDIT_ARCHIVE_BATCH entries. Each
+// file is synced to disk before its entries are deleted, so a failed run never
+// loses entries. It returns the number of entries archived.
+func ArchiveAuditLogs(db *gorm.DB, now time.Time) (int, error) {
+	auditArchiveMu.Lock()
+	defer auditArchiveMu.Unlock()
+
+	cutoff := now.Add(-auditRetention())
+	batchSize := envInt("AUDIT_ARCHIVE_BATCH", 10000)
+
+	archived := 0
+	err := os.MkdirAll(AuditArchiveDir(), 0o750)
+	for err == nil {
+		var entries []models.AuditLog
+		// The newest entry is kept so new entries still chain onto it
+		err = db.Where("created_at < ? AND sequence < (SELECT MAX(sequence) FROM audit_logs)", cutoff).
+			Order("sequence ASC").Limit(batchSize).Find(&entries).Error
+		if err != nil || len(entries) == 0 {
+			break
+		}
+
+		var path string
+		if path, err = writeAuditArchive(entries); err != nil {
+			break
+		}
+
+		ids := make([]string, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		err = db.Set(models.AuditArchiveKey, true).Where("id IN ?", ids).Delete(&models.AuditLog{}).Error
+		if err != nil {
+			break
+		}
+		archived += len(entries)
+		log.Printf("Archived %d audit log entries to %s", len(entries), path)
+	}
+
+	at := now
+	auditArchiveState.LastRunAt = &at
+	auditArchiveState.LastArchived = archived
+	auditArchiveState.TotalArchived += archived
+	auditArchiveState.LastError = ""
+	if err != nil {
+		auditArchiveState.LastError = err.Error()
+	}
+	return archived, err
+}
+
+// writeAuditArchive writes entries to a new archive file named after the
+// creation times of the first and last entry. An existing archive of the same
+// entries is left as it is.
+func writeAuditArchive(entries []models.AuditLog) (string, error) {
+	const stamp = "20060102T150405.000000Z"
+	name := fmt.Sprintf("audit-%s-%s.ndjson.gz",
+		entries[0].CreatedAt.UTC().Format(stamp), entries[len(entries)-1].CreatedAt.UTC().Format(stamp))
+	path := filepath.Join(AuditArchiveDir(), name)
+
+	// Write under a temporary name so a partial file is never taken for an archive
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	gz := gzip.NewWriter(file)
+	enc := json.NewEncoder(gz)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			file.Close()
+			return "", err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	// A run whose delete failed after the rename leaves the same batch behind,
+	// so an identical archive counts as written and its entries can go
+	if existing, err := os.ReadFile(path); err == nil {
+		written, err := os.ReadFile(tmp)
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(existing, written) {
+			return "", fmt.Errorf("archive %s already exists with different entries", name)
+		}
+		return path, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	return path, os.Rename(tmp, path)
+}
+
+// GetAuditArchiveStatus returns the retention settings, the last run and the archives on disk
+func GetAuditArchiveStatus(db *gorm.DB) (AuditArchiveStatus, error) {
+	auditArchiveMu.Lock()
+	status := auditArchiveState
+	auditArchiveMu.Unlock()
+
+	status.RetentionDays = int(auditRetention() / (24 * time.Hour))
+	status.Directory = AuditArchiveDir()
+
+	if err := db.Model(&models.AuditLog{}).Count(&status.DatabaseEntries).Error; err != nil {
+		return status, err
+	}
+	var oldest models.AuditLog
+	if err := db.Order("created_at ASC").Limit(1).Find(&oldest).Error; err != nil {
+		return status, err
+	}
+	if oldest.ID != "" {
+		status.OldestEntryAt = &oldest.CreatedAt
+	}
+
+	status.Archives = []AuditArchiveFile{}
+	dirEntries, err := os.ReadDir(status.Directory)
+	if err != nil && !os.IsNotExist(err) {
+		return status, err
+	}
+	for _, entry := range dirEntries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".ndjson.gz") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		status.Archives = append(status.Archives, AuditArchiveFile{Name: entry.Name(), Size: info.Size(), ModifiedAt: info.ModTime()})
+	}
+	sort.Slice(status.Archives, func(i, j int) bool { return status.Archives[i].Name < status.Archives[j].Name })
+	return status, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"backend/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testArchiveEntries() []models.AuditLog {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []models.AuditLog{
+		{ID: "a", Sequence: 1, Action: "login", CreatedAt: created},
+		{ID: "b", Sequence: 2, Action: "logout", CreatedAt: created.Add(time.Minute)},
+	}
+}
+
+func TestWriteAuditArchiveRewritesIdenticalBatch(t *testing.T) {
+	t.Setenv("AUDIT_ARCHIVE_DIR", t.TempDir())
+	entries := testArchiveEntries()
+
+	first, err := writeAuditArchive(entries)
+	if err != nil {
+		t.Fatalf("writeAuditArchive: %v", err)
+	}
+	// A previous run archived the batch but failed to delete it
+	second, err := writeAuditArchive(entries)
+	if err != nil {
+		t.Fatalf("writeAuditArchive of the same batch: %v", err)
+	}
+	if first != second {
+		t.Errorf("archive paths differ: %s, %s", first, second)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(AuditArchiveDir(), "*"))
+	if len(files) != 1 {
+		t.Errorf("archive directory holds %v, want a single archive", files)
+	}
+}
+
+func TestWriteAuditArchiveKeepsDifferentArchive(t *testing.T) {
+	t.Setenv("AUDIT_ARCHIVE_DIR", t.TempDir())
+	entries := testArchiveEntries()
+
+	path, err := writeAuditArchive(entries)
+	if err != nil {
+		t.Fatalf("writeAuditArchive: %v", err)
+	}
+	before, _ := os.ReadFile(path)
+
+	entries[1].Action = "delete_app"
+	if _, err := writeAuditArchive(entries); err == nil {
+		t.Fatal("writeAuditArchive over a different archive succeeded, want an error")
+	}
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) {
+		t.Error("existing archive was overwritten")
+	}
+}
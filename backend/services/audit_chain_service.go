@@ -0,0 +1,274 @@
+package services
+
+import (
+	"backend/models"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// auditChainLock is the Postgres advisory lock key that serializes appends to
+// the audit hash chain, so no two entries take the same place in it
+const auditChainLock = 0x61756469 // "audi"
+
+// auditHashContent is the hashed form of an entry. Its field order is part of
+// the chain format and must not change.
+type auditHashContent struct {
+	Sequence     int64   `json:"sequence"`
+	PrevHash     string  `json:"prevHash"`
+	CreatedAt    string  `json:"createdAt"`
+	UserID       *string `json:"userId"`
+	Username     string  `json:"username"`
+	Action       string  `json:"action"`
+	ResourceID   string  `json:"resourceId"`
+	ResourceType string  `json:"resourceType"`
+	ResourceName string  `json:"resourceName"`
+	Details      string  `json:"details"`
+	IPAddress    string  `json:"ipAddress"`
+	UserAgent    string  `json:"userAgent"`
+}
+
+// AuditLogHash returns the hex SHA-256 of an entry's content and PrevHash
+func AuditLogHash(entry models.AuditLog) string {
+	content, _ := json.Marshal(auditHashContent{
+		Sequence:     entry.Sequence,
+		PrevHash:     entry.PrevHash,
+		CreatedAt:    entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		UserID:       entry.UserID,
+		Username:     entry.Username,
+		Action:       entry.Action,
+		ResourceID:   entry.ResourceID,
+		ResourceType: entry.ResourceType,
+		ResourceName: entry.ResourceName,
+		Details:      entry.Details,
+		IPAddress:    entry.IPAddress,
+		UserAgent:    entry.UserAgent,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// lockAuditChain takes the chain lock until tx ends and returns the newest chained entry
+func lockAuditChain(tx *gorm.DB) (models.AuditLog, error) {
+	var head models.AuditLog
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+		return head, err
+	}
+	err := tx.Where("sequence IS NOT NULL").Order("sequence DESC").Limit(1).Find(&head).Error
+	return head, err
+}
+
+// appendAuditLog creates entry as the new head of the chain; tx must be a transaction
+func appendAuditLog(tx *gorm.DB, entry *models.AuditLog) error {
+	head, err := lockAuditChain(tx)
+	if err != nil {
+		return err
+	}
+
+	// Postgres stores microseconds, the hash must cover what is read back
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Sequence = head.Sequence + 1
+	entry.PrevHash = head.Hash
+	entry.Hash = AuditLogHash(*entry)
+	return tx.Create(entry).Error
+}
+
+// BackfillAuditChain chains entries written before the audit log was hash
+// chained, oldest first. It only runs while the chain is empty: once it has
+// started, an unchained entry was inserted behind its back and is left for
+// VerifyAuditChain to report.
+func BackfillAuditChain(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		head, err := lockAuditChain(tx)
+		if err != nil {
+			return err
+		}
+		if head.ID != "" {
+			return nil
+		}
+
+		var entries []models.AuditLog
+		if err := tx.Where("sequence IS NULL").Order("created_at ASC, id ASC").Find(&entries).Error; err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entry.Sequence = head.Sequence + 1
+			entry.PrevHash = head.Hash
+			entry.Hash = AuditLogHash(entry)
+			err := tx.Model(&entry).UpdateColumns(map[string]interface{}{
+				"sequence": entry.Sequence, "prev_hash": entry.PrevHash, "hash": entry.Hash,
+			}).Error
+			if err != nil {
+				return err
+			}
+			head = entry
+		}
+		if len(entries) > 0 {
+			log.Printf("Added %d existing audit log entries to the hash chain", len(entries))
+		}
+		return nil
+	})
+}
+
+// AuditChainReport is the result of verifying the audit hash chain
+type AuditChainReport struct {
+	Valid         bool   `json:"valid"`
+	Checked       int64  `json:"checked"`
+	FirstSequence int64  `json:"firstSequence"` // Entries before it were archived
+	LastSequence  int64  `json:"lastSequence"`
+	Unchained     int64  `json:"unchained"`          // Entries without a sequence
+	BrokenAt      int64  `json:"brokenAt,omitempty"` // Sequence of the first entry that does not verify
+	BrokenID      string `json:"brokenId,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// auditChainVerifier checks entries one by one, in sequence order, against
+// the entry before them. The first entry is checked against the anchor.
+type auditChainVerifier struct {
+	report   AuditChainReport
+	next     int64  // Sequence the next entry must have
+	prevHash string // Hash the next entry must link to
+}
+
+// newAuditChainVerifier starts a verifier at the given anchor: sequence 1 and
+// no previous hash for a chain that was never archived, otherwise the entry
+// after the last archived one
+func newAuditChainVerifier(next int64, prevHash string) *auditChainVerifier {
+	return &auditChainVerifier{next: next, prevHash: prevHash}
+}
+
+// check verifies entry and returns false once the chain is broken
+func (v *auditChainVerifier) check(entry models.AuditLog) bool {
+	reason := ""
+	switch {
+	case entry.Sequence != v.next && v.report.Checked == 0:
+		reason = fmt.Sprintf("chain starts at sequence %d instead of %d, entries are missing", entry.Sequence, v.next)
+	case entry.Sequence != v.next:
+		reason = fmt.Sprintf("sequence jumps from %d to %d, entries are missing", v.next-1, entry.Sequence)
+	case entry.PrevHash != v.prevHash && entry.Sequence == 1:
+		reason = "first entry of the chain links to a previous entry"
+	case entry.PrevHash != v.prevHash:
+		reason = fmt.Sprintf("previous hash does not match entry %d", entry.Sequence-1)
+	case AuditLogHash(entry) != entry.Hash:
+		reason = "hash does not match the entry's content"
+	}
+	if reason != "" {
+		v.report.BrokenAt = entry.Sequence
+		v.report.BrokenID = entry.ID
+		v.report.Reason = reason
+		return false
+	}
+
+	if v.report.Checked == 0 {
+		v.report.FirstSequence = entry.Sequence
+	}
+	v.report.LastSequence = entry.Sequence
+	v.report.Checked++
+	v.next = entry.Sequence + 1
+	v.prevHash = entry.Hash
+	return true
+}
+
+// finish completes the report once every entry has been checked
+func (v *auditChainVerifier) finish() AuditChainReport {
+	report := v.report
+	switch {
+	case report.BrokenID != "":
+	case report.Unchained > 0:
+		report.Reason = fmt.Sprintf("%d entries were inserted outside of the chain", report.Unchained)
+	case report.Checked == 0 && v.next > 1:
+		// The archiver always keeps the newest entry in the database
+		report.Reason = fmt.Sprintf("entries from sequence %d on are missing", v.next)
+	default:
+		report.Valid = true
+	}
+	return report
+}
+
+// auditChainAnchor returns the sequence and previous hash the oldest entry in
+// the database must have: those following the last entry of the newest
+// archive, or the start of the chain when nothing was archived
+func auditChainAnchor(dir string) (int64, string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 1, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	newest := ""
+	for _, entry := range dirEntries {
+		// Archive names start with the creation time of their first entry
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".ndjson.gz") && entry.Name() > newest {
+			newest = entry.Name()
+		}
+	}
+	if newest == "" {
+		return 1, "", nil
+	}
+
+	file, err := os.Open(filepath.Join(dir, newest))
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, "", fmt.Errorf("archive %s: %w", newest, err)
+	}
+	var last models.AuditLog
+	dec := json.NewDecoder(gz)
+	for dec.More() {
+		if err := dec.Decode(&last); err != nil {
+			return 0, "", fmt.Errorf("archive %s: %w", newest, err)
+		}
+	}
+	if last.ID == "" {
+		return 0, "", fmt.Errorf("archive %s is empty", newest)
+	}
+	return last.Sequence + 1, last.Hash, nil
+}
+
+// VerifyAuditChain walks the chain in sequence order and reports the first
+// entry whose hash does not match its content or that does not link to the
+// entry before it. The oldest entry must start the chain, or follow the last
+// entry of the newest archive.
+func VerifyAuditChain(db *gorm.DB) (AuditChainReport, error) {
+	next, prevHash, err := auditChainAnchor(AuditArchiveDir())
+	if err != nil {
+		return AuditChainReport{}, err
+	}
+	v := newAuditChainVerifier(next, prevHash)
+	if err := db.Model(&models.AuditLog{}).Where("sequence IS NULL").Count(&v.report.Unchained).Error; err != nil {
+		return v.report, err
+	}
+
+	rows, err := db.Model(&models.AuditLog{}).Where("sequence IS NOT NULL").Order("sequence ASC").Rows()
+	if err != nil {
+		return v.report, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditLog
+		if err := db.ScanRows(rows, &entry); err != nil {
+			return v.report, err
+		}
+		if !v.check(entry) {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return v.report, err
+	}
+	return v.finish(), nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAuditChain builds n correctly chained entries starting at sequence 1
+func testAuditChain(n int) []models.AuditLog {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := make([]models.AuditLog, n)
+	prevHash := ""
+	for i := range entries {
+		entries[i] = models.AuditLog{
+			ID:           string(rune('a' + i)),
+			Sequence:     int64(i + 1),
+			PrevHash:     prevHash,
+			CreatedAt:    created.Add(time.Duration(i) * time.Minute),
+			Username:     "admin",
+			Action:       "update_app",
+			ResourceType: "app",
+			Details:      "App updated",
+		}
+		entries[i].Hash = AuditLogHash(entries[i])
+		prevHash = entries[i].Hash
+	}
+	return entries
+}
+
+func verifyTestChain(entries []models.AuditLog, next int64, prevHash string) AuditChainReport {
+	v := newAuditChainVerifier(next, prevHash)
+	for _, entry := range entries {
+		if !v.check(entry) {
+			break
+		}
+	}
+	return v.finish()
+}
+
+func TestAuditLogHash(t *testing.T) {
+	entry := testAuditChain(1)[0]
+	if AuditLogHash(entry) != entry.Hash {
+		t.Fatal("AuditLogHash is not deterministic")
+	}
+
+	changed := entry
+	changed.Details = "App deleted"
+	if AuditLogHash(changed) == entry.Hash {
+		t.Error("hash does not cover details")
+	}
+	changed = entry
+	changed.PrevHash = "00"
+	if AuditLogHash(changed) == entry.Hash {
+		t.Error("hash does not cover the previous hash")
+	}
+	// Only the instant counts, not the time zone it is read back in
+	changed = entry
+	changed.CreatedAt = entry.CreatedAt.In(time.FixedZone("CET", 3600))
+	if AuditLogHash(changed) != entry.Hash {
+		t.Error("hash depends on the time zone of createdAt")
+	}
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	full := testAuditChain(5)
+
+	tests := []struct {
+		name     string
+		entries  func() []models.AuditLog
+		next     int64
+		prevHash string
+		brokenAt int64
+		reason   string
+	}{
+		{
+			name:    "intact",
+			entries: func() []models.AuditLog { return full },
+			next:    1,
+		},
+		{
+			name:    "empty",
+			entries: func() []models.AuditLog { return nil },
+			next:    1,
+		},
+		{
+			name: "tampered field",
+			entries: func() []models.AuditLog {
+				entries := append([]models.AuditLog(nil), full...)
+				entries[2].Username = "someone-else"
+				return entries
+			},
+			next:     1,
+			brokenAt: 3,
+			reason:   "hash does not match",
+		},
+		{
+			name: "tampered and rehashed",
+			entries: func() []models.AuditLog {
+				entries := append([]models.AuditLog(nil), full...)
+				entries[2].Username = "someone-else"
+				entries[2].Hash = AuditLogHash(entries[2])
+				return entries
+			},
+			next:     1,
+			brokenAt: 4,
+			reason:   "previous hash does not match entry 3",
+		},
+		{
+			name: "deleted middle row",
+			entries: func() []models.AuditLog {
+				return append(append([]models.AuditLog(nil), full[:2]...), full[3:]...)
+			},
+			next:     1,
+			brokenAt: 4,
+			reason:   "sequence jumps from 2 to 4",
+		},
+		{
+			name:     "deleted prefix",
+			entries:  func() []models.AuditLog { return full[2:] },
+			next:     1,
+			brokenAt: 3,
+			reason:   "chain starts at sequence 3 instead of 1",
+		},
+		{
+			name:     "archived prefix",
+			entries:  func() []models.AuditLog { return full[2:] },
+			next:     3,
+			prevHash: full[1].Hash,
+		},
+		{
+			name:     "deleted prefix after archive",
+			entries:  func() []models.AuditLog { return full[3:] },
+			next:     3,
+			prevHash: full[1].Hash,
+			brokenAt: 4,
+			reason:   "chain starts at sequence 4 instead of 3",
+		},
+		{
+			name:     "archive does not match",
+			entries:  func() []models.AuditLog { return full[2:] },
+			next:     3,
+			prevHash: full[0].Hash,
+			brokenAt: 3,
+			reason:   "previous hash does not match entry 2",
+		},
+		{
+			name:     "everything after archive deleted",
+			entries:  func() []models.AuditLog { return nil },
+			next:     6,
+			prevHash: full[4].Hash,
+			reason:   "entries from sequence 6 on are missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := verifyTestChain(tt.entries(), tt.next, tt.prevHash)
+			if tt.reason == "" {
+				if !report.Valid {
+					t.Fatalf("chain reported invalid: %+v", report)
+				}
+				return
+			}
+			if report.Valid {
+				t.Fatalf("chain reported valid, want %q", tt.reason)
+			}
+			if report.BrokenAt != tt.brokenAt || !strings.Contains(report.Reason, tt.reason) {
+				t.Errorf("broken at %d: %q, want %d: %q", report.BrokenAt, report.Reason, tt.brokenAt, tt.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditChainUnchained(t *testing.T) {
+	v := newAuditChainVerifier(1, "")
+	v.report.Unchained = 1
+	for _, entry := range testAuditChain(3) {
+		v.check(entry)
+	}
+	if report := v.finish(); report.Valid {
+		t.Error("chain with an unchained entry reported valid")
+	}
+}
+
+func TestAuditChainAnchor(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AUDIT_ARCHIVE_DIR", dir)
+
+	next, prevHash, err := auditChainAnchor(dir)
+	if err != nil || next != 1 || prevHash != "" {
+		t.Fatalf("anchor without archives = %d %q %v, want 1", next, prevHash, err)
+	}
+
+	chain := testAuditChain(5)
+	if _, err := writeAuditArchive(chain[:2]); err != nil {
+		t.Fatalf("writeAuditArchive: %v", err)
+	}
+	if _, err := writeAuditArchive(chain[2:4]); err != nil {
+		t.Fatalf("writeAuditArchive: %v", err)
+	}
+	next, prevHash, err = auditChainAnchor(dir)
+	if err != nil || next != 5 || prevHash != chain[3].Hash {
+		t.Errorf("anchor = %d %q %v, want 5 %q", next, prevHash, err, chain[3].Hash)
+	}
+}
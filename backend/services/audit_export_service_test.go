@@ -0,0 +1,23 @@
+package services
+
+import "testing"
+
+func TestCSVSafe(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"", ""},
+		{"web-app", "web-app"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+	}
+	for _, tt := range tests {
+		if got := csvSafe(tt.value); got != tt.want {
+			t.Errorf("csvSafe(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -3,14 +3,23 @@ package services
 import (
 	"backend/models"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// LogAction creates an audit log entry
-func LogAction(db *gorm.DB, c *gin.Context, action, resourceType, resourceID, resourceName, details string) error {
+// Actor identifies who performed an audited action
+type Actor struct {
+	UserID    string
+	Username  string
+	IPAddress string
+	UserAgent string
+}
+
+// ActorFromContext resolves the authenticated user of a request
+func ActorFromContext(db *gorm.DB, c *gin.Context) Actor {
 	// Get user info from context
 	username := "unknown"
 	if user, exists := c.Get("username"); exists {
@@ -24,38 +33,106 @@ func LogAction(db *gorm.DB, c *gin.Context, action, resourceType, resourceID, re
 		userID = dbUser.ID
 	}
 
+	return Actor{
+		UserID:    userID,
+		Username:  username,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+}
+
+// SystemActor is used for actions WebManager performs on its own, e.g. "auto-stop"
+func SystemActor(name string) Actor {
+	return Actor{Username: name}
+}
+
+// LogAction creates an audit log entry
+func LogAction(db *gorm.DB, c *gin.Context, action, resourceType, resourceID, resourceName, details string) error {
+	return LogActionAs(db, ActorFromContext(db, c), action, resourceType, resourceID, resourceName, details)
+}
+
+// LogActionAs creates an audit log entry for the given actor
+func LogActionAs(db *gorm.DB, actor Actor, action, resourceType, resourceID, resourceName, details string) error {
+	_, err := CreateAuditLog(db, actor, action, resourceType, resourceID, resourceName, details)
+	return err
+}
+
+// CreateAuditLog creates an audit log entry and returns it, e.g. to link records to it
+func CreateAuditLog(db *gorm.DB, actor Actor, action, resourceType, resourceID, resourceName, details string) (models.AuditLog, error) {
 	auditLog := models.AuditLog{
-		UserID:       userID,
-		Username:     username,
+		Username:     actor.Username,
 		Action:       action,
 		ResourceID:   resourceID,
 		ResourceType: resourceType,
 		ResourceName: resourceName,
 		Details:      details,
-		IPAddress:    c.ClientIP(),
-		UserAgent:    c.GetHeader("User-Agent"),
+		IPAddress:    actor.IPAddress,
+		UserAgent:    actor.UserAgent,
+	}
+	if actor.UserID != "" {
+		auditLog.UserID = &actor.UserID
 	}
 
-	return db.Create(&auditLog).Error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return appendAuditLog(tx, &auditLog)
+	})
+	return auditLog, err
 }
 
-// GetAuditLogs retrieves audit logs with pagination and filtering
-func GetAuditLogs(db *gorm.DB, limit, offset int, userID, action, resourceType string) ([]models.AuditLog, int64, error) {
-	var logs []models.AuditLog
-	var total int64
+// AuditLogFilter selects audit log entries; empty fields match everything
+type AuditLogFilter struct {
+	UserID       string
+	Action       string
+	ResourceType string
+	From         *time.Time // Inclusive
+	To           *time.Time // Exclusive
+}
 
-	query := db.Model(&models.AuditLog{})
+func (f AuditLogFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.UserID != "" {
+		query = query.Where("user_id = ?", f.UserID)
+	}
+	if f.Action != "" {
+		query = query.Where("action = ?", f.Action)
+	}
+	if f.ResourceType != "" {
+		query = query.Where("resource_type = ?", f.ResourceType)
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at < ?", *f.To)
+	}
+	return query
+}
 
-	// Apply filters
-	if userID != "" {
-		query = query.Where("user_id = ?", userID)
+// String describes the filter for audit details, e.g. "action=start_app, from=..."
+func (f AuditLogFilter) String() string {
+	var parts []string
+	for _, p := range [][2]string{{"userId", f.UserID}, {"action", f.Action}, {"resourceType", f.ResourceType}} {
+		if p[1] != "" {
+			parts = append(parts, p[0]+"="+p[1])
+		}
+	}
+	if f.From != nil {
+		parts = append(parts, "from="+f.From.UTC().Format(time.RFC3339))
 	}
-	if action != "" {
-		query = query.Where("action = ?", action)
+	if f.To != nil {
+		parts = append(parts, "to="+f.To.UTC().Format(time.RFC3339))
 	}
-	if resourceType != "" {
-		query = query.Where("resource_type = ?", resourceType)
+	if len(parts) == 0 {
+		return "all entries"
 	}
+	return strings.Join(parts, ", ")
+}
+
+// GetAuditLogs retrieves audit logs with pagination and filtering
+func GetAuditLogs(db *gorm.DB, limit, offset int, filter AuditLogFilter) ([]models.AuditLog, int64, error) {
+	var logs []models.AuditLog
+	var total int64
+
+	query := filter.apply(db.Model(&models.AuditLog{}))
 
 	// Get total count
 	query.Count(&total)
@@ -68,10 +145,19 @@ func GetAuditLogs(db *gorm.DB, limit, offset int, userID, action, resourceType s
 
 // LogAppAction logs app-specific actions with duration
 func LogAppAction(db *gorm.DB, c *gin.Context, action string, app models.App, duration *time.Duration) error {
+	return LogAppActionAs(db, ActorFromContext(db, c), action, app, duration)
+}
+
+// LogAppActionAs logs app-specific actions with duration for the given actor
+func LogAppActionAs(db *gorm.DB, actor Actor, action string, app models.App, duration *time.Duration) error {
+	return LogActionAs(db, actor, action, "app", app.ID, app.Name, AppActionDetails(app, duration))
+}
+
+// AppActionDetails describes an app action for the audit log
+func AppActionDetails(app models.App, duration *time.Duration) string {
 	details := fmt.Sprintf("App: %s on server %s", app.Name, app.ServerID)
 	if duration != nil {
 		details += fmt.Sprintf(", Duration: %s", duration.String())
 	}
-
-	return LogAction(db, c, action, "app", app.ID, app.Name, details)
-}
\ No newline at end of file
+	return details
+}
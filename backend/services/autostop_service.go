@@ -0,0 +1,129 @@
+package services
+
+import (
+	"backend/metrics"
+	"backend/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// pendingAutoStops holds apps with a queued auto-stop so a slow stop is not queued twice
+var pendingAutoStops sync.Map
+
+// autoStopPostponeTicks is how many sweeps an auto-stop blocked by running
+// dependents waits before it is tried again
+const autoStopPostponeTicks = 10
+
+// autoStopInterval returns how often the sweeper runs (AUTO_STOP_INTERVAL_SECONDS)
+func autoStopInterval() time.Duration {
+	return time.Duration(envInt("AUTO_STOP_INTERVAL_SECONDS", 30)) * time.Second
+}
+
+// StartAutoStopSweeper periodically stops running apps whose timer has ended
+// or that have been idle too long
+func StartAutoStopSweeper(db *gorm.DB) {
+	interval := autoStopInterval()
+	log.Printf("Auto-stop sweeper running every %s", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			warnExpiringApps(db)
+			sweepExpiredApps(db)
+			sweepIdleApps(db)
+		}
+	}()
+}
+
+// warnExpiringApps emails the starter of each app that auto-stops within
+// NOTIFY_EXPIRY_MINUTES, once per run
+func warnExpiringApps(db *gorm.DB) {
+	minutes := envInt("NOTIFY_EXPIRY_MINUTES", 10)
+	now := time.Now()
+
+	var apps []models.App
+	err := db.Where("status = ? AND expiry_warned = ? AND timer_ends_at > ? AND timer_ends_at <= ?",
+		"running", false, now.Unix(), now.Add(time.Duration(minutes)*time.Minute).Unix()).
+		Find(&apps).Error
+	if err != nil {
+		log.Printf("Auto-stop sweeper could not load expiring apps: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		remaining := int(time.Until(time.Unix(*app.TimerEndsAt, 0)).Round(time.Minute).Minutes())
+		NotifyAppExpiring(db, app, remaining)
+		db.Model(&app).Update("expiry_warned", true)
+	}
+}
+
+func sweepExpiredApps(db *gorm.DB) {
+	var apps []models.App
+	err := db.Where("status = ? AND timer_ends_at IS NOT NULL AND timer_ends_at <= ?", "running", time.Now().Unix()).
+		Find(&apps).Error
+	if err != nil {
+		log.Printf("Auto-stop sweeper could not load apps: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		if _, queued := pendingAutoStops.LoadOrStore(app.ID, true); queued {
+			continue
+		}
+		appID := app.ID
+		if err := EnqueueJob("auto-stop "+app.Name, func() { autoStopApp(db, appID) }); err != nil {
+			pendingAutoStops.Delete(appID)
+			log.Printf("Auto-stop of app %s not queued: %v", app.Name, err)
+		}
+	}
+}
+
+func autoStopApp(db *gorm.DB, appID string) {
+	defer pendingAutoStops.Delete(appID)
+
+	// Reload: the app may have been stopped or its timer extended while queued
+	var app models.App
+	if err := db.First(&app, "id = ?", appID).Error; err != nil {
+		return
+	}
+	if app.Status != "running" || app.TimerEndsAt == nil || *app.TimerEndsAt > time.Now().Unix() {
+		return
+	}
+
+	// A reservation keeps the app up until it ends; move the timer there so
+	// the sweeper does not pick the app up again on every tick
+	if reservation := ActiveReservation(db, app.ID, time.Now()); reservation != nil {
+		db.Model(&app).Updates(map[string]interface{}{"timer_ends_at": reservation.EndsAt.Unix(), "expiry_warned": false})
+		log.Printf("Auto-stop of app %s postponed until the end of its reservation by %s", app.Name, reservation.Username)
+		return
+	}
+
+	// Dependencies outlive their timer while a running app needs them; the
+	// timer moves forward so the stop is retried later, not on every tick
+	if dependents, err := RunningDependents(db, app.ID); err == nil && len(dependents) > 0 {
+		retryAt := time.Now().Add(autoStopPostponeTicks * autoStopInterval())
+		db.Model(&app).Update("timer_ends_at", retryAt.Unix())
+		log.Printf("Auto-stop of app %s postponed until %s: required by %s",
+			app.Name, retryAt.Format(time.RFC3339), DependentNames(dependents))
+		return
+	}
+
+	server, err := GetServerByID(db, app.ServerID)
+	if err != nil {
+		log.Printf("Auto-stop of app %s failed: server not found", app.Name)
+		metrics.AutoStopExecutions.WithLabelValues("failure").Inc()
+		return
+	}
+
+	if _, err := StopApp(db, server, &app, SystemActor("auto-stop"), "auto_stop_app", ""); err != nil {
+		log.Printf("Auto-stop of app %s failed: %v", app.Name, err)
+		metrics.AutoStopExecutions.WithLabelValues("failure").Inc()
+		return
+	}
+	log.Printf("Auto-stopped app %s", app.Name)
+	metrics.AutoStopExecutions.WithLabelValues("success").Inc()
+}
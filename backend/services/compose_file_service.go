@@ -0,0 +1,278 @@
+package services
+
+import (
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"gorm.io/gorm"
+)
+
+// composeFileNames are the compose files docker-compose picks up by default,
+// base files first. Only these can be read or written through the API.
+var composeFileNames = []string{
+	"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml",
+	"docker-compose.override.yml", "docker-compose.override.yaml",
+	"compose.override.yml", "compose.override.yaml",
+}
+
+// maxComposeFileSize guards against reading something that is not a compose file
+const maxComposeFileSize = 1 << 20
+
+var (
+	// composeEnvironment starts an environment: block, or holds it inline
+	composeEnvironment = regexp.MustCompile(`^(\s*)environment:\s*(.*)$`)
+	// composeEnvItem is a KEY=value list item or KEY: value map item of an
+	// environment: block; the value is the last group
+	composeEnvItem = regexp.MustCompile(`^(\s*-\s*["']?[^=\s"']+=|\s*["']?[^:\s"']+["']?:\s*)(\S.*)$`)
+	// composeSecretItem is any setting whose name ends in PASSWORD, SECRET or TOKEN
+	composeSecretItem = regexp.MustCompile(`(?i)^(\s*(?:-\s*)?["']?[A-Za-z0-9_.-]*(?:PASSWORD|SECRET|TOKEN)["']?\s*(?:=|:\s))\s*(\S.*)$`)
+)
+
+// ErrComposeInvalid wraps the output of a failed `docker-compose config`
+var ErrComposeInvalid = errors.New("compose configuration is invalid")
+
+// ComposeFile is a compose file as read from the app's server
+type ComposeFile struct {
+	Name       string    `json:"name"`
+	Content    string    `json:"content"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// IsComposeFileName reports whether name is one of the default compose file names
+func IsComposeFileName(name string) bool {
+	return slices.Contains(composeFileNames, name)
+}
+
+// withSFTP runs fn with an SFTP client connected to the server
+func withSFTP(server models.Server, fn func(client *sftp.Client) error) error {
+	start := time.Now()
+	conn, err := utils.DialSSH(server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey)
+	if err != nil {
+		recordSSHCommand(server, start, err)
+		return err
+	}
+	defer conn.Close()
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		recordSSHCommand(server, start, err)
+		return err
+	}
+	defer client.Close()
+
+	err = fn(client)
+	recordSSHCommand(server, start, err)
+	return err
+}
+
+func readRemoteFile(client *sftp.Client, name string) (ComposeFile, error) {
+	file, err := client.Open(name)
+	if err != nil {
+		return ComposeFile{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return ComposeFile{}, err
+	}
+	if info.Size() > maxComposeFileSize {
+		return ComposeFile{}, fmt.Errorf("%s is larger than %d bytes", name, maxComposeFileSize)
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return ComposeFile{}, err
+	}
+	return ComposeFile{Name: path.Base(name), Content: string(content), Size: info.Size(), ModifiedAt: info.ModTime()}, nil
+}
+
+func writeRemoteFile(client *sftp.Client, name, content string, mode os.FileMode) error {
+	file, err := client.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write([]byte(content)); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return client.Chmod(name, mode)
+}
+
+// ListComposeFiles reads every default compose file present in the app's directory
+func ListComposeFiles(server models.Server, app models.App) ([]ComposeFile, error) {
+	files := []ComposeFile{}
+	err := withSFTP(server, func(client *sftp.Client) error {
+		for _, name := range composeFileNames {
+			file, err := readRemoteFile(client, path.Join(app.ComposePath, name))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// WriteComposeFile validates new content for one of the app's compose files
+// with `docker-compose config`, backs up the current version next to it and
+// replaces it. The returned diff is also recorded in the audit log, with
+// environment values and secrets masked.
+func WriteComposeFile(db *gorm.DB, server models.Server, app models.App, name, content string, actor Actor) (string, string, error) {
+	if !IsComposeFileName(name) {
+		return "", "", fmt.Errorf("%s is not a compose file name", name)
+	}
+	target := path.Join(app.ComposePath, name)
+	tmp := path.Join(app.ComposePath, "."+name+".webmanager-new")
+
+	var old ComposeFile
+	var exists bool
+	var present []string
+	err := withSFTP(server, func(client *sftp.Client) error {
+		var err error
+		old, err = readRemoteFile(client, target)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return err
+		default:
+			exists = true
+		}
+		for _, candidate := range composeFileNames {
+			if _, err := client.Stat(path.Join(app.ComposePath, candidate)); err == nil {
+				present = append(present, candidate)
+			}
+		}
+		return writeRemoteFile(client, tmp, content, 0644)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := validateComposeFiles(server, app, name, tmp, present); err != nil {
+		withSFTP(server, func(client *sftp.Client) error { return client.Remove(tmp) })
+		return "", "", err
+	}
+
+	backup := ""
+	err = withSFTP(server, func(client *sftp.Client) error {
+		if exists {
+			backup = fmt.Sprintf("%s.%s.bak", name, time.Now().Format("20060102-150405"))
+			if err := writeRemoteFile(client, path.Join(app.ComposePath, backup), old.Content, 0644); err != nil {
+				return fmt.Errorf("could not write backup: %w", err)
+			}
+		}
+		return client.PosixRename(tmp, target)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	diff := utils.UnifiedDiff("a/"+name, "b/"+name, old.Content, content)
+	details := "Edited " + name
+	if backup != "" {
+		details += " (backup: " + backup + ")"
+	}
+	// The audit log keeps the diff with environment values and secrets masked
+	if audited := utils.MaskedUnifiedDiff("a/"+name, "b/"+name, old.Content, content, maskComposeSecrets); audited == "" {
+		details += ", no changes"
+	} else {
+		details += "\n" + audited
+	}
+	LogActionAs(db, actor, "edit_compose_file", "app", app.ID, app.Name, details)
+
+	return backup, diff, nil
+}
+
+// maskComposeSecrets replaces the values of environment: entries and of
+// settings named *PASSWORD, *SECRET or *TOKEN in the lines of a compose file
+func maskComposeSecrets(lines []string) []string {
+	masked := make([]string, len(lines))
+	envIndent := -1 // Indentation of the environment: key the lines are under
+	for i, line := range lines {
+		masked[i] = line
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if envIndent >= 0 && indent <= envIndent {
+			envIndent = -1
+		}
+
+		if m := composeEnvironment.FindStringSubmatch(line); m != nil {
+			if m[2] == "" || strings.HasPrefix(m[2], "#") {
+				envIndent = len(m[1])
+			} else {
+				masked[i] = m[1] + "environment: " + MaskedValue
+			}
+			continue
+		}
+		if envIndent >= 0 {
+			if m := composeEnvItem.FindStringSubmatch(line); m != nil {
+				masked[i] = m[1] + MaskedValue
+			}
+			continue
+		}
+		if m := composeSecretItem.FindStringSubmatch(line); m != nil {
+			masked[i] = m[1] + MaskedValue
+		}
+	}
+	return masked
+}
+
+// validateComposeFiles runs `docker-compose config -q` over the compose files
+// of the app, with the edited file replaced by its uploaded candidate
+func validateComposeFiles(server models.Server, app models.App, name, candidate string, present []string) error {
+	// A base file is validated with the overrides applied to it, an override
+	// with the base file it applies to
+	args := ""
+	if isOverrideFile(name) {
+		base := ""
+		for _, file := range present {
+			if !isOverrideFile(file) {
+				base = file
+				break
+			}
+		}
+		if base == "" {
+			return fmt.Errorf("%w: no base compose file to apply %s to", ErrComposeInvalid, name)
+		}
+		args = " -f " + base + " -f " + path.Base(candidate)
+	} else {
+		args = " -f " + path.Base(candidate)
+		for _, file := range present {
+			if isOverrideFile(file) {
+				args += " -f " + file
+			}
+		}
+	}
+
+	cmd := fmt.Sprintf("cd %s && docker-compose%s config -q 2>&1", app.ComposePath, args)
+	if out, err := runSSH(server, cmd); err != nil {
+		return fmt.Errorf("%w: %s", ErrComposeInvalid, strings.TrimSpace(out))
+	}
+	return nil
+}
+
+func isOverrideFile(name string) bool {
+	return strings.Contains(name, ".override.")
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaskComposeSecrets(t *testing.T) {
+	compose := `services:
+  db:
+    image: postgres:16
+    environment:
+      POSTGRES_USER: app
+      # comment
+      POSTGRES_PASSWORD: "hunter2"
+    ports:
+      - "5432:5432"
+  web:
+    image: web:latest
+    environment:
+      - DATABASE_URL=postgres://app:hunter2@db/app
+      - DEBUG
+    labels:
+      traefik.http.middlewares.auth.basicauth.password: hunter2
+    command: ["--api-token", "abc"]
+    build:
+      args:
+        - NPM_TOKEN=abc
+  cache:
+    environment: {REDIS_PASSWORD: hunter2}
+`
+	want := `services:
+  db:
+    image: postgres:16
+    environment:
+      POSTGRES_USER: ********
+      # comment
+      POSTGRES_PASSWORD: ********
+    ports:
+      - "5432:5432"
+  web:
+    image: web:latest
+    environment:
+      - DATABASE_URL=********
+      - DEBUG
+    labels:
+      traefik.http.middlewares.auth.basicauth.password: ********
+    command: ["--api-token", "abc"]
+    build:
+      args:
+        - NPM_TOKEN=********
+  cache:
+    environment: ********
+`
+	lines := strings.Split(compose, "\n")
+	masked := maskComposeSecrets(lines)
+	if len(masked) != len(lines) {
+		t.Fatalf("maskComposeSecrets returned %d lines, want %d", len(masked), len(lines))
+	}
+	if got := strings.Join(masked, "\n"); got != want {
+		t.Errorf("maskComposeSecrets() =\n%s\nwant\n%s", got, want)
+	}
+}
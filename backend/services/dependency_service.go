@@ -0,0 +1,305 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// dependencyGraph maps each app ID to the IDs of the apps it depends on
+type dependencyGraph map[string][]string
+
+func loadDependencyGraph(db *gorm.DB) (dependencyGraph, error) {
+	var deps []models.AppDependency
+	if err := db.Find(&deps).Error; err != nil {
+		return nil, err
+	}
+	graph := dependencyGraph{}
+	for _, dep := range deps {
+		graph[dep.AppID] = append(graph[dep.AppID], dep.DependsOnID)
+	}
+	return graph, nil
+}
+
+// order returns the transitive dependencies of appID, each after its own
+// dependencies, or an error naming the cycle if there is one
+func (g dependencyGraph) order(appID string) ([]string, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var order []string
+	var path []string
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), id)
+		}
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range g[id] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		order = append(order, id)
+		return nil
+	}
+
+	if err := visit(appID); err != nil {
+		return nil, err
+	}
+	return order[:len(order)-1], nil
+}
+
+// ValidateDependencies checks that the apps exist and that making appID depend
+// on them does not create a cycle. appID may be empty for a new app.
+func ValidateDependencies(db *gorm.DB, appID string, dependsOn []string) error {
+	for _, id := range dependsOn {
+		if id == appID {
+			return fmt.Errorf("an app cannot depend on itself")
+		}
+		var count int64
+		db.Model(&models.App{}).Where("id = ?", id).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("dependency %s not found", id)
+		}
+	}
+	if appID == "" {
+		// Nothing can depend on an app that does not exist yet
+		return nil
+	}
+
+	graph, err := loadDependencyGraph(db)
+	if err != nil {
+		return err
+	}
+	graph[appID] = dependsOn
+	if _, err := graph.order(appID); err != nil {
+		return fmt.Errorf("dependency cycle: %s", appNames(db, err))
+	}
+	return nil
+}
+
+// appNames makes a cycle error readable by listing the names of the apps involved
+func appNames(db *gorm.DB, cycle error) string {
+	var apps []models.App
+	db.Select("id", "name").Find(&apps)
+	names := cycle.Error()
+	for _, app := range apps {
+		names = strings.ReplaceAll(names, app.ID, app.Name)
+	}
+	return strings.TrimPrefix(names, "dependency cycle: ")
+}
+
+// SetDependencies replaces the dependencies of an app
+func SetDependencies(db *gorm.DB, appID string, dependsOn []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("app_id = ?", appID).Delete(&models.AppDependency{}).Error; err != nil {
+			return err
+		}
+		seen := map[string]bool{}
+		for _, id := range dependsOn {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if err := tx.Create(&models.AppDependency{AppID: appID, DependsOnID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteDependencies removes every dependency from or to an app
+func DeleteDependencies(db *gorm.DB, appID string) error {
+	return db.Where("app_id = ? OR depends_on_id = ?", appID, appID).Delete(&models.AppDependency{}).Error
+}
+
+// RunningDependents returns the running apps that directly or indirectly
+// depend on an app, each before the apps it depends on (i.e. in stop order)
+func RunningDependents(db *gorm.DB, appID string) ([]models.App, error) {
+	graph, err := loadDependencyGraph(db)
+	if err != nil {
+		return nil, err
+	}
+	reverse := dependencyGraph{}
+	for id, deps := range graph {
+		for _, dep := range deps {
+			reverse[dep] = append(reverse[dep], id)
+		}
+	}
+	// In the reversed graph an app's dependents come before it, so reverse the order
+	ids, err := reverse.order(appID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var apps []models.App
+	if err := db.Where("id IN ? AND status = ?", ids, "running").Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	byID := map[string]models.App{}
+	for _, app := range apps {
+		byID[app.ID] = app
+	}
+	dependents := []models.App{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		if app, ok := byID[ids[i]]; ok {
+			dependents = append(dependents, app)
+		}
+	}
+	return dependents, nil
+}
+
+// startDependencies starts the stopped dependencies of an app in topological
+// order and waits for every dependency to be healthy
+func startDependencies(db *gorm.DB, app *models.App, actor Actor, timeoutMinutes int) error {
+	graph, err := loadDependencyGraph(db)
+	if err != nil {
+		return err
+	}
+	ids, err := graph.order(app.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		var dep models.App
+		if err := db.First(&dep, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("dependency %s not found", id)
+		}
+		server, err := GetServerByID(db, dep.ServerID)
+		if err != nil {
+			return fmt.Errorf("server not found for dependency %s", dep.Name)
+		}
+
+		if dep.Status != "running" {
+			log.Printf("Starting %s as a dependency of %s", dep.Name, app.Name)
+			if _, err := startApp(db, server, &dep, actor, "dependency_start_app", timeoutMinutes); err != nil {
+				return fmt.Errorf("failed to start dependency %s: %w", dep.Name, err)
+			}
+			beginHealthCheck(db, &dep, actor)
+		}
+		if err := WaitForHealthy(server, dep); err != nil {
+			return fmt.Errorf("dependency %s is not healthy: %w", dep.Name, err)
+		}
+	}
+	return nil
+}
+
+// WaitForHealthy polls the containers of an app until all of them run and
+// pass their health check, for up to DEPENDENCY_HEALTH_TIMEOUT_SECONDS.
+// Containers without a health check count as healthy once running.
+func WaitForHealthy(server models.Server, app models.App) error {
+	timeout := time.Duration(envInt("DEPENDENCY_HEALTH_TIMEOUT_SECONDS", 120)) * time.Second
+	deadline := time.Now().Add(timeout)
+	cmd := fmt.Sprintf("cd %s && docker-compose ps -q | xargs -r docker inspect -f '{{.Name}} {{.State.Status}} {{if .State.Health}}{{.State.Health.Status}}{{end}}'", app.ComposePath)
+
+	for {
+		out, err := runSSH(server, cmd)
+		if err != nil {
+			return err
+		}
+		pending, err := containerHealth(out)
+		if err != nil {
+			return err
+		}
+		if pending == "" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s", timeout, pending)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// containerHealth parses `docker inspect` lines of "<name> <status> [<health>]".
+// It returns the name of a container that is not ready yet, or an error if one
+// has exited or is unhealthy.
+func containerHealth(out string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return "containers", nil
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[0], "/")
+		health := ""
+		if len(fields) > 2 {
+			health = fields[2]
+		}
+		switch {
+		case fields[1] == "exited" || fields[1] == "dead":
+			return "", fmt.Errorf("container %s has %s", name, fields[1])
+		case health == "unhealthy":
+			return "", fmt.Errorf("container %s is unhealthy", name)
+		case fields[1] != "running" || health == "starting":
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// DependentNames lists the names of apps, e.g. for "required by" messages
+func DependentNames(apps []models.App) string {
+	names := make([]string, len(apps))
+	for i, app := range apps {
+		names[i] = app.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// StopDependents stops the given running dependents of an app, most dependent first
+func StopDependents(db *gorm.DB, app models.App, dependents []models.App, actor Actor) error {
+	for _, dep := range dependents {
+		server, err := GetServerByID(db, dep.ServerID)
+		if err != nil {
+			return fmt.Errorf("server not found for dependent %s", dep.Name)
+		}
+		if _, err := StopApp(db, server, &dep, actor, "cascade_stop_app", "Dependency "+app.Name+" stopped"); err != nil {
+			return fmt.Errorf("failed to stop dependent %s: %w", dep.Name, err)
+		}
+	}
+	return nil
+}
+
+// PopulateDependencies fills the DependsOn field of apps
+func PopulateDependencies(db *gorm.DB, apps []models.App) {
+	ids := make([]string, len(apps))
+	for i, app := range apps {
+		ids[i] = app.ID
+	}
+	var deps []models.AppDependency
+	db.Where("app_id IN ?", ids).Order("created_at").Find(&deps)
+
+	byApp := map[string][]string{}
+	for _, dep := range deps {
+		byApp[dep.AppID] = append(byApp[dep.AppID], dep.DependsOnID)
+	}
+	for i := range apps {
+		apps[i].DependsOn = byApp[apps[i].ID]
+		if apps[i].DependsOn == nil {
+			apps[i].DependsOn = []string{}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ServiceImageChange compares the image of a service before and after a redeploy
+type ServiceImageChange struct {
+	Service   string `json:"service"`
+	Image     string `json:"image"`
+	OldDigest string `json:"oldDigest"`
+	NewDigest string `json:"newDigest"`
+	Changed   bool   `json:"changed"`
+}
+
+// ComposeImages returns the images of the running containers of an app, by service
+func ComposeImages(server models.Server, app models.App) ([]models.ServiceImage, error) {
+	cmd := fmt.Sprintf(`cd %s && docker-compose ps -q | xargs -r docker inspect -f '{{index .Config.Labels "com.docker.compose.service"}} {{.Config.Image}} {{.Image}}'`, app.ComposePath)
+	out, err := runSSH(server, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("could not inspect containers: %w", err)
+	}
+
+	byService := map[string]models.ServiceImage{}
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		byService[fields[0]] = models.ServiceImage{Service: fields[0], Image: fields[1], ImageID: fields[2]}
+		ids = append(ids, fields[2])
+	}
+	if len(ids) == 0 {
+		return []models.ServiceImage{}, nil
+	}
+
+	// Repository digests identify the image across hosts, unlike local IDs
+	out, err = runSSH(server, "docker image inspect -f '{{.Id}} {{join .RepoDigests \",\"}}' "+strings.Join(ids, " "))
+	if err != nil {
+		return nil, fmt.Errorf("could not inspect images: %w", err)
+	}
+	digests := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		id, list, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if list != "" {
+			digests[id] = strings.Split(list, ",")
+		}
+	}
+
+	images := make([]models.ServiceImage, 0, len(byService))
+	for _, image := range byService {
+		image.Digest = repoDigest(image.Image, digests[image.ImageID])
+		images = append(images, image)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Service < images[j].Service })
+	return images, nil
+}
+
+// repoDigest picks the digest of the repository an image reference points to
+func repoDigest(image string, digests []string) string {
+	repo := image
+	if at := strings.Index(repo, "@"); at >= 0 {
+		repo = repo[:at]
+	} else if colon := strings.LastIndex(repo, ":"); colon > strings.LastIndex(repo, "/") {
+		repo = repo[:colon]
+	}
+	for _, digest := range digests {
+		if strings.HasPrefix(digest, repo+"@") || strings.HasPrefix(digest, "docker.io/library/"+repo+"@") {
+			return digest
+		}
+	}
+	if len(digests) > 0 {
+		return digests[0]
+	}
+	return ""
+}
+
+// imageVersion is what identifies the version of a service image: its digest, or its local ID
+func imageVersion(image models.ServiceImage) string {
+	if image.Digest != "" {
+		return image.Digest
+	}
+	return image.ImageID
+}
+
+// compareImages reports, per service, whether its image changed
+func compareImages(before, after []models.ServiceImage) []ServiceImageChange {
+	old := map[string]models.ServiceImage{}
+	for _, image := range before {
+		old[image.Service] = image
+	}
+	changes := []ServiceImageChange{}
+	for _, image := range after {
+		change := ServiceImageChange{Service: image.Service, Image: image.Image, NewDigest: imageVersion(image)}
+		if previous, ok := old[image.Service]; ok {
+			change.OldDigest = imageVersion(previous)
+		}
+		change.Changed = change.OldDigest != change.NewDigest
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// RedeployResult is the outcome of a redeploy
+type RedeployResult struct {
+	Output   string               `json:"output"`
+	Services []ServiceImageChange `json:"services"`
+}
+
+// RedeployApp pulls the images of a running app and recreates the containers
+// whose image changed, auditing the digests before and after
+func RedeployApp(db *gorm.DB, server models.Server, app *models.App, actor Actor) (RedeployResult, error) {
+	before, err := ComposeImages(server, *app)
+	if err != nil {
+		return RedeployResult{}, err
+	}
+	// Redeploying goes back to the images of the compose files after a rollback
+	if err := unpinApp(db, server, app); err != nil {
+		return RedeployResult{}, err
+	}
+	if err := UploadEnvFile(db, server, *app); err != nil {
+		return RedeployResult{}, err
+	}
+
+	cmd := fmt.Sprintf("cd %s && docker-compose pull 2>&1 && docker-compose up -d --remove-orphans 2>&1", app.ComposePath)
+	out, err := runSSH(server, cmd)
+	if err != nil {
+		return RedeployResult{Output: out}, fmt.Errorf("command failed: %w", err)
+	}
+
+	after, err := ComposeImages(server, *app)
+	if err != nil {
+		return RedeployResult{Output: out}, err
+	}
+	result := RedeployResult{Output: out, Services: compareImages(before, after)}
+	recordDeployment(db, *app, "redeploy", after, nil, actor)
+
+	setLastAction(app, "redeploy_app", actor, time.Now())
+	db.Save(app)
+
+	LogActionAs(db, actor, "redeploy_app", "app", app.ID, app.Name, redeployDetails(result.Services))
+	EmitAppEvent(db, EventAppRedeployed, *app, actor)
+	beginHealthCheck(db, app, actor)
+
+	return result, nil
+}
+
+func redeployDetails(changes []ServiceImageChange) string {
+	var parts []string
+	for _, change := range changes {
+		switch {
+		case !change.Changed:
+			parts = append(parts, fmt.Sprintf("%s unchanged (%s)", change.Service, change.NewDigest))
+		case change.OldDigest == "":
+			parts = append(parts, fmt.Sprintf("%s new (%s)", change.Service, change.NewDigest))
+		default:
+			parts = append(parts, fmt.Sprintf("%s %s -> %s", change.Service, change.OldDigest, change.NewDigest))
+		}
+	}
+	if len(parts) == 0 {
+		return "Redeployed, no running services"
+	}
+	return "Redeployed: " + strings.Join(parts, "; ")
+}
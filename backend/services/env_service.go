@@ -0,0 +1,298 @@
+package services
+
+import (
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaskedValue replaces secret values in API responses
+const MaskedValue = "********"
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvKey checks that a key is a valid environment variable name
+func ValidateEnvKey(key string) error {
+	if !envKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid variable name %q", key)
+	}
+	return nil
+}
+
+// ListEnvVars returns the variables of an app sorted by key, with secrets decrypted
+func ListEnvVars(db *gorm.DB, appID string) ([]models.AppEnvVar, error) {
+	var vars []models.AppEnvVar
+	if err := db.Where("app_id = ?", appID).Order("key").Find(&vars).Error; err != nil {
+		return nil, err
+	}
+	for i := range vars {
+		if vars[i].Secret {
+			value, err := utils.Decrypt(vars[i].Value)
+			if err != nil {
+				return nil, fmt.Errorf("could not decrypt %s: %w", vars[i].Key, err)
+			}
+			vars[i].Value = value
+		}
+	}
+	return vars, nil
+}
+
+// MaskEnvVars hides the values of secret variables
+func MaskEnvVars(vars []models.AppEnvVar) []models.AppEnvVar {
+	masked := make([]models.AppEnvVar, len(vars))
+	for i, v := range vars {
+		if v.Secret {
+			v.Value = MaskedValue
+		}
+		masked[i] = v
+	}
+	return masked
+}
+
+// SetEnvVar creates or updates a variable of an app and records the change.
+// A nil value keeps the current one, e.g. to only mark a variable secret.
+func SetEnvVar(db *gorm.DB, app models.App, key string, value *string, secret *bool, actor Actor) (models.AppEnvVar, error) {
+	if err := ValidateEnvKey(key); err != nil {
+		return models.AppEnvVar{}, err
+	}
+
+	var envVar models.AppEnvVar
+	err := db.Transaction(func(tx *gorm.DB) error {
+		change := models.AppEnvChange{AppID: app.ID, Key: key, Username: actor.Username}
+		if actor.UserID != "" {
+			change.UserID = &actor.UserID
+		}
+
+		err := tx.Where("app_id = ? AND key = ?", app.ID, key).First(&envVar).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if value == nil {
+				return fmt.Errorf("value is required for new variable %s", key)
+			}
+			envVar = models.AppEnvVar{AppID: app.ID, Key: key}
+			change.Action = "create"
+		case err != nil:
+			return err
+		default:
+			change.Action = "update"
+			if envVar.Secret {
+				if envVar.Value, err = utils.Decrypt(envVar.Value); err != nil {
+					return err
+				}
+			}
+			change.OldValue = envVar.Value
+		}
+
+		wasSecret := envVar.Secret
+		if value != nil {
+			envVar.Value = *value
+		}
+		if secret != nil {
+			envVar.Secret = *secret
+		}
+		change.NewValue = envVar.Value
+		change.Secret = envVar.Secret || wasSecret
+		if change.Secret {
+			change.OldValue, change.NewValue = "", ""
+		}
+
+		plain := envVar.Value
+		if envVar.Secret {
+			encrypted, err := utils.Encrypt(plain)
+			if err != nil {
+				return fmt.Errorf("could not encrypt %s: %w", key, err)
+			}
+			envVar.Value = encrypted
+		}
+		if err := tx.Save(&envVar).Error; err != nil {
+			return err
+		}
+		envVar.Value = plain
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return models.AppEnvVar{}, err
+	}
+
+	LogActionAs(db, actor, "set_env_var", "app", app.ID, app.Name, "Set "+key)
+	return envVar, nil
+}
+
+// DeleteEnvVar removes a variable of an app and records the change
+func DeleteEnvVar(db *gorm.DB, app models.App, key string, actor Actor) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var envVar models.AppEnvVar
+		if err := tx.Where("app_id = ? AND key = ?", app.ID, key).First(&envVar).Error; err != nil {
+			return err
+		}
+		change := models.AppEnvChange{AppID: app.ID, Key: key, Action: "delete", Secret: envVar.Secret, Username: actor.Username}
+		if !envVar.Secret {
+			change.OldValue = envVar.Value
+		}
+		if actor.UserID != "" {
+			change.UserID = &actor.UserID
+		}
+		if err := tx.Delete(&envVar).Error; err != nil {
+			return err
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	LogActionAs(db, actor, "delete_env_var", "app", app.ID, app.Name, "Deleted "+key)
+	return nil
+}
+
+// RenderEnvFile formats variables as a compose .env file
+func RenderEnvFile(vars []models.AppEnvVar) string {
+	sorted := append([]models.AppEnvVar(nil), vars...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+
+	var b strings.Builder
+	b.WriteString("# Managed by WebManager, changes made here are overwritten on the next start\n")
+	for _, v := range sorted {
+		b.WriteString(v.Key + "=" + quoteEnvValue(v.Value) + "\n")
+	}
+	return b.String()
+}
+
+// quoteEnvValue single-quotes values that need it so compose reads them
+// literally; values with single quotes or newlines are double-quoted instead
+func quoteEnvValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r#'\"$\\`") {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", "$$")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// parseEnvFile reads KEY=VALUE lines, ignoring comments and removing quotes
+func parseEnvFile(content string) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		} else if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			replacer := strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", "$$", "$")
+			value = replacer.Replace(value[1 : len(value)-1])
+		}
+		values[key] = value
+	}
+	return values
+}
+
+func envFilePath(app models.App) string {
+	return strings.TrimRight(app.ComposePath, "/") + "/.env"
+}
+
+// envManaged reports whether WebManager owns an app's .env file, i.e. the app
+// has or has had managed variables
+func envManaged(db *gorm.DB, appID string, vars []models.AppEnvVar) bool {
+	if len(vars) > 0 {
+		return true
+	}
+	var changes int64
+	db.Model(&models.AppEnvChange{}).Where("app_id = ?", appID).Count(&changes)
+	return changes > 0
+}
+
+// UploadEnvFile writes the rendered .env file next to the compose file.
+// Apps that never had managed variables keep whatever .env they have; once
+// the last one is deleted the file is emptied so deleted values do not linger.
+func UploadEnvFile(db *gorm.DB, server models.Server, app models.App) error {
+	vars, err := ListEnvVars(db, app.ID)
+	if err != nil {
+		return err
+	}
+	if !envManaged(db, app.ID, vars) {
+		return nil
+	}
+
+	path := envFilePath(app)
+	cmd := fmt.Sprintf("umask 077 && cat > %s.tmp && mv %s.tmp %s", path, path, path)
+	out, err := runSSHWithInput(server, cmd, RenderEnvFile(vars))
+	if err != nil {
+		return fmt.Errorf("could not write %s: %v: %s", path, err, strings.TrimSpace(out))
+	}
+	log.Printf("Wrote %d variables to %s on %s", len(vars), path, server.Address)
+	return nil
+}
+
+// EnvDiffEntry is one variable that differs between WebManager and the server
+type EnvDiffEntry struct {
+	Key      string `json:"key"`
+	Change   string `json:"change"` // "added", "changed", "removed"
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+	Secret   bool   `json:"secret"`
+}
+
+// EnvDiff compares the managed variables of an app with the .env file
+// currently on its server, i.e. what the next start will change
+func EnvDiff(db *gorm.DB, server models.Server, app models.App) ([]EnvDiffEntry, error) {
+	vars, err := ListEnvVars(db, app.ID)
+	if err != nil {
+		return nil, err
+	}
+	out, err := runSSH(server, fmt.Sprintf("cat %s 2>/dev/null || true", envFilePath(app)))
+	if err != nil {
+		return nil, err
+	}
+	current := parseEnvFile(out)
+
+	diff := []EnvDiffEntry{}
+	if !envManaged(db, app.ID, vars) {
+		return diff, nil
+	}
+	managed := map[string]bool{}
+	for _, v := range vars {
+		managed[v.Key] = true
+		old, exists := current[v.Key]
+		entry := EnvDiffEntry{Key: v.Key, OldValue: old, NewValue: v.Value, Secret: v.Secret}
+		switch {
+		case !exists:
+			entry.Change = "added"
+		case old != v.Value:
+			entry.Change = "changed"
+		default:
+			continue
+		}
+		if v.Secret {
+			entry.OldValue, entry.NewValue = "", MaskedValue
+			if exists {
+				entry.OldValue = MaskedValue
+			}
+		}
+		diff = append(diff, entry)
+	}
+	for key := range current {
+		if !managed[key] {
+			// Unmanaged values may be secrets set by hand, so they are not shown
+			diff = append(diff, EnvDiffEntry{Key: key, Change: "removed", OldValue: MaskedValue})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Key < diff[j].Key })
+	return diff, nil
+}
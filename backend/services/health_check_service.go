@@ -0,0 +1,156 @@
+package services
+
+import (
+	"backend/models"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// pendingHealthChecks holds apps with a running health check so a restart does not start a second one
+var pendingHealthChecks sync.Map
+
+// HealthCheckURL resolves the URL of a health check: full URLs are used
+// as-is, paths are appended to the app's AppURL, or to https://<Domain>
+func HealthCheckURL(app models.App, check models.AppHealthCheck) string {
+	if strings.HasPrefix(check.URL, "http://") || strings.HasPrefix(check.URL, "https://") {
+		return check.URL
+	}
+	base := app.AppURL
+	if base == "" {
+		base = "https://" + app.Domain
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(check.URL, "/")
+}
+
+// probeHealth makes one request and checks its status and body
+func probeHealth(url string, check models.AppHealthCheck) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if check.SkipTLSVerify {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != check.ExpectedStatus {
+		return fmt.Errorf("got HTTP %d, expected %d", resp.StatusCode, check.ExpectedStatus)
+	}
+	if check.BodyMatch != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(body), check.BodyMatch) {
+			return fmt.Errorf("response body does not contain %q", check.BodyMatch)
+		}
+	}
+	return nil
+}
+
+// VerifyAppHealth polls the health check of an app every IntervalSeconds
+// until it passes or TimeoutSeconds have passed, and returns the last error
+func VerifyAppHealth(app models.App, check models.AppHealthCheck) error {
+	url := HealthCheckURL(app, check)
+	interval := time.Duration(max(check.IntervalSeconds, 1)) * time.Second
+	deadline := time.Now().Add(time.Duration(check.TimeoutSeconds) * time.Second)
+	for {
+		err := probeHealth(url, check)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// setAppHealth stores the health of an app without touching its other columns
+func setAppHealth(db *gorm.DB, app *models.App, status, message string) {
+	now := time.Now()
+	app.HealthStatus = status
+	app.HealthMessage = message
+	app.HealthCheckedAt = &now
+	db.Model(&models.App{}).Where("id = ?", app.ID).Updates(map[string]interface{}{
+		"health_status":     status,
+		"health_message":    message,
+		"health_checked_at": now,
+	})
+}
+
+// beginHealthCheck marks a just started app as "starting" and verifies its
+// health check in the background. Apps without an enabled check are left alone.
+func beginHealthCheck(db *gorm.DB, app *models.App, actor Actor) {
+	var check models.AppHealthCheck
+	if err := db.Where("app_id = ? AND enabled = ?", app.ID, true).First(&check).Error; err != nil {
+		setAppHealth(db, app, "", "")
+		return
+	}
+	setAppHealth(db, app, "starting", "Waiting for "+HealthCheckURL(*app, check))
+
+	if _, running := pendingHealthChecks.LoadOrStore(app.ID, true); running {
+		return
+	}
+	// Polls wait up to TimeoutSeconds, so they get their own goroutine instead
+	// of holding a job worker that stops and wakes apps
+	appID := app.ID
+	go runJob(job{name: "health check " + app.Name, run: func() {
+		defer pendingHealthChecks.Delete(appID)
+		runHealthCheck(db, appID, check, actor)
+	}})
+}
+
+func runHealthCheck(db *gorm.DB, appID string, check models.AppHealthCheck, actor Actor) {
+	var app models.App
+	if err := db.First(&app, "id = ?", appID).Error; err != nil {
+		return
+	}
+	err := VerifyAppHealth(app, check)
+
+	// The app may have been stopped while it was checked
+	db.First(&app, "id = ?", appID)
+	if app.Status != "running" {
+		return
+	}
+	if err != nil {
+		log.Printf("App %s is unhealthy: %v", app.Name, err)
+		setAppHealth(db, &app, "unhealthy", err.Error())
+		LogActionAs(db, actor, "health_check_failed", "app", app.ID, app.Name, err.Error())
+		EmitAppEvent(db, EventAppUnhealthy, app, actor)
+		NotifyStartFailed(db, app, actor.UserID, errors.New("the app did not become healthy: "+err.Error()))
+		return
+	}
+	setAppHealth(db, &app, "healthy", "")
+	EmitAppEvent(db, EventAppHealthy, app, actor)
+}
+
+// WaitForHealthStatus waits until the health check started for an app
+// finishes, and returns the app as last stored
+func WaitForHealthStatus(db *gorm.DB, appID string) models.App {
+	var check models.AppHealthCheck
+	timeout := 0
+	if db.Where("app_id = ?", appID).First(&check).Error == nil {
+		timeout = check.TimeoutSeconds
+	}
+	deadline := time.Now().Add(time.Duration(timeout+15) * time.Second)
+
+	var app models.App
+	for {
+		db.First(&app, "id = ?", appID)
+		if app.HealthStatus != "starting" || time.Now().After(deadline) {
+			return app
+		}
+		time.Sleep(time.Second)
+	}
+}
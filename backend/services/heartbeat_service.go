@@ -0,0 +1,61 @@
+package services
+
+import (
+	"backend/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAppNotRunning is returned for a heartbeat to an app that is not running
+var ErrAppNotRunning = errors.New("app is not running")
+
+// heartbeatCeiling returns the latest time heartbeats may push an app's
+// auto-stop timer to, HEARTBEAT_MAX_HOURS after it was started
+func heartbeatCeiling(app models.App) time.Time {
+	maxRun := time.Duration(envInt("HEARTBEAT_MAX_HOURS", 12)) * time.Hour
+	if app.StartedAt == nil {
+		return time.Now().Add(maxRun)
+	}
+	return app.StartedAt.Add(maxRun)
+}
+
+// RecordHeartbeat marks a running app as in use by actor. Apps with an
+// auto-stop timer get it extended to HEARTBEAT_EXTEND_MINUTES from now, but
+// never past the ceiling; capped reports whether the ceiling was reached.
+func RecordHeartbeat(db *gorm.DB, app *models.App, actor Actor) (capped bool, err error) {
+	if app.Status != "running" {
+		return false, ErrAppNotRunning
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_heartbeat_at": now,
+		"last_heartbeat_by": actor.Username,
+		"last_active_at":    now,
+		"idle_warned_at":    nil,
+	}
+
+	if app.TimerEndsAt != nil {
+		timerEndsAt := now.Add(time.Duration(envInt("HEARTBEAT_EXTEND_MINUTES", 30)) * time.Minute)
+		if ceiling := heartbeatCeiling(*app); timerEndsAt.After(ceiling) {
+			timerEndsAt = ceiling
+			capped = true
+		}
+		// Heartbeats only ever push the timer back, a longer run stays as it is
+		if timerEndsAt.Unix() > *app.TimerEndsAt {
+			updates["timer_ends_at"] = timerEndsAt.Unix()
+			updates["expiry_warned"] = false
+		}
+	}
+
+	result := db.Model(app).Where("status = ?", "running").Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, ErrAppNotRunning
+	}
+	return capped, nil
+}
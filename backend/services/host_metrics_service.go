@@ -0,0 +1,151 @@
+package services
+
+import (
+	"backend/models"
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hostMetricsScript prints /proc and df readings in sections marked with "--name".
+// /proc/stat is sampled twice, one second apart, to compute CPU usage.
+const hostMetricsScript = `echo '--loadavg'; cat /proc/loadavg
+echo '--stat'; head -n1 /proc/stat; sleep 1; head -n1 /proc/stat
+echo '--meminfo'; grep -E '^(MemTotal|MemAvailable):' /proc/meminfo
+echo '--uptime'; cat /proc/uptime
+echo '--disk'
+root=$(docker info --format '{{.DockerRootDir}}' 2>/dev/null)
+[ -d "$root" ] || root=/var/lib/docker
+[ -d "$root" ] || root=/
+echo "$root"; df -Pk "$root" | tail -n1`
+
+// CollectHostMetrics gathers CPU, load, memory, docker root disk and uptime from a server
+func CollectHostMetrics(server models.Server) (models.HostMetrics, error) {
+	output, err := runSSH(server, hostMetricsScript)
+	if err != nil {
+		return models.HostMetrics{}, fmt.Errorf("failed to collect host metrics: %w", err)
+	}
+
+	metrics, err := parseHostMetrics(output)
+	if err != nil {
+		return models.HostMetrics{}, fmt.Errorf("failed to parse host metrics: %w", err)
+	}
+	now := time.Now().Unix()
+	metrics.CollectedAt = &now
+	return metrics, nil
+}
+
+func parseHostMetrics(output string) (models.HostMetrics, error) {
+	sections := map[string][]string{}
+	current := ""
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "--") {
+			current = strings.TrimPrefix(line, "--")
+			continue
+		}
+		if current != "" && line != "" {
+			sections[current] = append(sections[current], line)
+		}
+	}
+
+	var metrics models.HostMetrics
+
+	// /proc/loadavg: "0.52 0.58 0.59 1/467 12345"
+	loadavg := sections["loadavg"]
+	if len(loadavg) == 0 {
+		return metrics, fmt.Errorf("missing /proc/loadavg output")
+	}
+	fields := strings.Fields(loadavg[0])
+	if len(fields) < 3 {
+		return metrics, fmt.Errorf("unexpected /proc/loadavg output: %q", loadavg[0])
+	}
+	metrics.LoadAvg1, _ = strconv.ParseFloat(fields[0], 64)
+	metrics.LoadAvg5, _ = strconv.ParseFloat(fields[1], 64)
+	metrics.LoadAvg15, _ = strconv.ParseFloat(fields[2], 64)
+
+	// Two "cpu ..." lines from /proc/stat
+	stat := sections["stat"]
+	if len(stat) < 2 {
+		return metrics, fmt.Errorf("missing /proc/stat samples")
+	}
+	idle1, total1, err := parseCPUStatLine(stat[0])
+	if err != nil {
+		return metrics, err
+	}
+	idle2, total2, err := parseCPUStatLine(stat[1])
+	if err != nil {
+		return metrics, err
+	}
+	if total2 > total1 {
+		busy := float64((total2 - total1) - (idle2 - idle1))
+		metrics.CPUUsage = busy / float64(total2-total1) * 100
+	}
+
+	// /proc/meminfo values are in kB
+	for _, line := range sections["meminfo"] {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			metrics.MemTotal = kb * 1024
+		case "MemAvailable:":
+			metrics.MemAvailable = kb * 1024
+		}
+	}
+
+	// /proc/uptime: "350735.47 234388.90"
+	if uptime := sections["uptime"]; len(uptime) > 0 {
+		if fields := strings.Fields(uptime[0]); len(fields) > 0 {
+			if seconds, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				metrics.Uptime = int64(seconds)
+			}
+		}
+	}
+
+	// Docker root dir followed by "Filesystem 1024-blocks Used Available Capacity Mounted"
+	if disk := sections["disk"]; len(disk) >= 2 {
+		metrics.DockerRootDir = disk[0]
+		fields := strings.Fields(disk[1])
+		if len(fields) >= 4 {
+			total, _ := strconv.ParseInt(fields[1], 10, 64)
+			free, _ := strconv.ParseInt(fields[3], 10, 64)
+			metrics.DiskTotal = total * 1024
+			metrics.DiskFree = free * 1024
+		}
+	}
+
+	return metrics, nil
+}
+
+// parseCPUStatLine returns idle and total jiffies from the aggregate "cpu" line of /proc/stat
+func parseCPUStatLine(line string) (idle, total uint64, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected /proc/stat line: %q", line)
+	}
+	// user nice system idle iowait irq softirq steal; guest time is already counted in user
+	for i, field := range fields[1:] {
+		if i >= 8 {
+			break
+		}
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unexpected /proc/stat value %q: %w", field, err)
+		}
+		total += value
+		if i == 3 || i == 4 {
+			idle += value
+		}
+	}
+	return idle, total, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+)
+
+const sampleHostMetricsOutput = `--loadavg
+0.52 0.58 0.59 1/467 12345
+--stat
+cpu  100 0 100 800 0 0 0 0 0 0
+cpu  150 0 150 900 0 0 0 0 0 0
+--meminfo
+MemTotal:        8000000 kB
+MemAvailable:    2000000 kB
+--uptime
+350735.47 234388.90
+--disk
+/var/lib/docker
+/dev/sda1 102400 51200 40960 56% /
+`
+
+func TestParseHostMetrics(t *testing.T) {
+	metrics, err := parseHostMetrics(sampleHostMetricsOutput)
+	if err != nil {
+		t.Fatalf("parseHostMetrics: %v", err)
+	}
+
+	if metrics.LoadAvg1 != 0.52 || metrics.LoadAvg5 != 0.58 || metrics.LoadAvg15 != 0.59 {
+		t.Errorf("load averages = %v %v %v", metrics.LoadAvg1, metrics.LoadAvg5, metrics.LoadAvg15)
+	}
+	// 200 jiffies passed, 100 of them idle
+	if metrics.CPUUsage != 50 {
+		t.Errorf("CPUUsage = %v, want 50", metrics.CPUUsage)
+	}
+	if metrics.MemTotal != 8000000*1024 || metrics.MemAvailable != 2000000*1024 {
+		t.Errorf("memory = %d/%d", metrics.MemAvailable, metrics.MemTotal)
+	}
+	if metrics.Uptime != 350735 {
+		t.Errorf("Uptime = %d, want 350735", metrics.Uptime)
+	}
+	if metrics.DockerRootDir != "/var/lib/docker" || metrics.DiskTotal != 102400*1024 || metrics.DiskFree != 40960*1024 {
+		t.Errorf("disk = %q %d/%d", metrics.DockerRootDir, metrics.DiskFree, metrics.DiskTotal)
+	}
+}
+
+func TestParseHostMetricsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{"empty", ""},
+		{"missing loadavg", "--stat\ncpu 1 0 1 8 0 0 0 0\ncpu 2 0 2 9 0 0 0 0\n"},
+		{"short loadavg", "--loadavg\n0.52\n"},
+		{"single cpu sample", "--loadavg\n0.5 0.5 0.5 1/1 1\n--stat\ncpu 1 0 1 8 0 0 0 0\n"},
+		{"bad cpu line", "--loadavg\n0.5 0.5 0.5 1/1 1\n--stat\ncpu0 1 0 1 8\ncpu 2 0 2 9 0\n"},
+		{"bad cpu value", "--loadavg\n0.5 0.5 0.5 1/1 1\n--stat\ncpu 1 x 1 8 0\ncpu 2 0 2 9 0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseHostMetrics(tt.output); err == nil {
+				t.Errorf("parseHostMetrics(%q) succeeded, want an error", tt.output)
+			}
+		})
+	}
+}
+
+func TestParseHostMetricsOptionalSections(t *testing.T) {
+	output := "--loadavg\n1 2 3 1/1 1\n--stat\ncpu 10 0 0 10 0\ncpu 10 0 0 10 0\n"
+	metrics, err := parseHostMetrics(output)
+	if err != nil {
+		t.Fatalf("parseHostMetrics: %v", err)
+	}
+	// No time passed between samples, no memory, uptime or disk sections
+	if metrics.CPUUsage != 0 || metrics.MemTotal != 0 || metrics.Uptime != 0 || metrics.DiskTotal != 0 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
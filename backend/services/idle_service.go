@@ -0,0 +1,167 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// netSample is the cumulative network traffic of an app at a point in time
+type netSample struct {
+	bytes int64
+	at    time.Time
+}
+
+// lastNetSamples holds the previous netSample per app ID to turn counters into rates
+var lastNetSamples sync.Map
+
+// pendingIdleStops holds apps with a queued idle stop so it is not queued twice
+var pendingIdleStops sync.Map
+
+type idleThresholds struct {
+	cpuPercent  float64
+	netKBPerMin float64
+	grace       time.Duration
+}
+
+func getIdleThresholds() idleThresholds {
+	return idleThresholds{
+		cpuPercent:  float64(envInt("IDLE_CPU_PERCENT", 2)),
+		netKBPerMin: float64(envInt("IDLE_NET_KB_PER_MIN", 50)),
+		grace:       time.Duration(envInt("IDLE_GRACE_MINUTES", 5)) * time.Minute,
+	}
+}
+
+// UpdateAppActivity records when a running app was last active, judged by its
+// docker stats and by requests through the built-in proxy
+func UpdateAppActivity(db *gorm.DB, app models.App, stats AppStats, at time.Time) {
+	thresholds := getIdleThresholds()
+	active := stats.CPUUsage >= thresholds.cpuPercent
+
+	current := netSample{bytes: stats.NetRxBytes + stats.NetTxBytes, at: at}
+	if prev, ok := lastNetSamples.Load(app.ID); ok {
+		prev := prev.(netSample)
+		// Counters go backwards when containers restart, which counts as activity
+		if minutes := at.Sub(prev.at).Minutes(); minutes > 0 {
+			rate := float64(current.bytes-prev.bytes) / 1024 / minutes
+			if rate < 0 || rate >= thresholds.netKBPerMin {
+				active = true
+			}
+		}
+	}
+	lastNetSamples.Store(app.ID, current)
+
+	lastActive := app.LastActiveAt
+	if active {
+		lastActive = &at
+	}
+	if last, ok := LastAppActivity(app.ID); ok && (lastActive == nil || last.After(*lastActive)) {
+		lastActive = &last
+	}
+	if lastActive == nil || (app.LastActiveAt != nil && !lastActive.After(*app.LastActiveAt)) {
+		return
+	}
+
+	updates := map[string]interface{}{"last_active_at": *lastActive}
+	if app.IdleWarnedAt != nil {
+		updates["idle_warned_at"] = nil
+		log.Printf("App %s is active again, idle stop cancelled", app.Name)
+	}
+	db.Model(&models.App{}).Where("id = ? AND status = ?", app.ID, "running").Updates(updates)
+}
+
+// sweepIdleApps warns about running apps idle for their IdleStopMinutes and
+// stops them once the grace period has passed without activity
+func sweepIdleApps(db *gorm.DB) {
+	now := time.Now()
+	grace := getIdleThresholds().grace
+
+	var apps []models.App
+	err := db.Where("status = ? AND idle_stop_minutes > 0 AND last_active_at IS NOT NULL", "running").Find(&apps).Error
+	if err != nil {
+		log.Printf("Idle sweeper could not load apps: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		if now.Sub(*app.LastActiveAt) < time.Duration(app.IdleStopMinutes)*time.Minute {
+			continue
+		}
+		// Reserved apps are held for someone and stay up while idle
+		if ActiveReservation(db, app.ID, now) != nil {
+			continue
+		}
+		// So are apps that running apps depend on; no warning is sent for a
+		// stop that cannot happen
+		if dependents, err := RunningDependents(db, app.ID); err != nil || len(dependents) > 0 {
+			if err == nil && app.IdleWarnedAt != nil {
+				postponeIdleStop(db, app, dependents)
+			}
+			continue
+		}
+
+		if app.IdleWarnedAt == nil {
+			stopsAt := now.Add(grace)
+			NotifyAppIdle(db, app, int(grace.Minutes()), stopsAt)
+			db.Model(&app).Update("idle_warned_at", now)
+			LogActionAs(db, SystemActor("idle-stop"), "idle_warning", "app", app.ID, app.Name,
+				fmt.Sprintf("Idle for %d minutes, stopping at %s", app.IdleStopMinutes, stopsAt.Format(time.RFC3339)))
+			continue
+		}
+		if now.Sub(*app.IdleWarnedAt) < grace {
+			continue
+		}
+
+		if _, queued := pendingIdleStops.LoadOrStore(app.ID, true); queued {
+			continue
+		}
+		appID := app.ID
+		if err := EnqueueJob("idle-stop "+app.Name, func() { idleStopApp(db, appID) }); err != nil {
+			pendingIdleStops.Delete(appID)
+			log.Printf("Idle stop of app %s not queued: %v", app.Name, err)
+		}
+	}
+}
+
+func idleStopApp(db *gorm.DB, appID string) {
+	defer pendingIdleStops.Delete(appID)
+
+	// Reload: activity may have cancelled the warning while queued
+	var app models.App
+	if err := db.First(&app, "id = ?", appID).Error; err != nil {
+		return
+	}
+	if app.Status != "running" || app.IdleWarnedAt == nil {
+		return
+	}
+
+	if dependents, err := RunningDependents(db, app.ID); err == nil && len(dependents) > 0 {
+		postponeIdleStop(db, app, dependents)
+		return
+	}
+
+	server, err := GetServerByID(db, app.ServerID)
+	if err != nil {
+		log.Printf("Idle stop of app %s failed: server not found", app.Name)
+		return
+	}
+
+	reason := fmt.Sprintf("Idle for %d minutes", app.IdleStopMinutes)
+	if _, err := StopApp(db, server, &app, SystemActor("idle-stop"), "idle_stop_app", reason); err != nil {
+		log.Printf("Idle stop of app %s failed: %v", app.Name, err)
+		return
+	}
+	lastNetSamples.Delete(app.ID)
+	log.Printf("Stopped idle app %s", app.Name)
+}
+
+// postponeIdleStop withdraws the idle warning of an app that running apps
+// depend on and restarts its idle period
+func postponeIdleStop(db *gorm.DB, app models.App, dependents []models.App) {
+	db.Model(&app).Updates(map[string]interface{}{"idle_warned_at": nil, "last_active_at": time.Now()})
+	log.Printf("Idle stop of app %s postponed: required by %s", app.Name, DependentNames(dependents))
+}
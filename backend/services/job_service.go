@@ -0,0 +1,50 @@
+package services
+
+import (
+	"backend/metrics"
+	"fmt"
+	"log"
+)
+
+// job is a unit of background work such as an automatic stop
+type job struct {
+	name string
+	run  func()
+}
+
+var jobQueue = make(chan job, 256)
+
+func init() {
+	metrics.RegisterJobQueueDepth(func() int { return len(jobQueue) })
+}
+
+// StartJobWorkers starts workers that run queued background jobs
+func StartJobWorkers(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range jobQueue {
+				runJob(j)
+			}
+		}()
+	}
+	log.Printf("Started %d background job workers", workers)
+}
+
+func runJob(j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %q panicked: %v", j.name, r)
+		}
+	}()
+	j.run()
+}
+
+// EnqueueJob queues work for the background workers without blocking
+func EnqueueJob(name string, run func()) error {
+	select {
+	case jobQueue <- job{name: name, run: run}:
+		return nil
+	default:
+		return fmt.Errorf("job queue is full, dropping %q", name)
+	}
+}
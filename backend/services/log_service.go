@@ -0,0 +1,84 @@
+package services
+
+import (
+	"backend/models"
+	"backend/utils"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	logSincePattern    = regexp.MustCompile(`^([0-9]+(ns|us|ms|s|m|h))+$`)
+)
+
+// LogOptions selects the container logs of an app
+type LogOptions struct {
+	Services   []string // All services when empty
+	Tail       string   // Number of lines per container, or "all"
+	Since      string   // RFC3339 time, unix timestamp or relative duration like "10m"
+	Timestamps bool
+	Follow     bool
+}
+
+// Validate checks the options before they are put into a shell command
+func (o LogOptions) Validate() error {
+	for _, service := range o.Services {
+		if !serviceNamePattern.MatchString(service) {
+			return fmt.Errorf("invalid service name %q", service)
+		}
+	}
+	if o.Tail != "all" {
+		if n, err := strconv.Atoi(o.Tail); err != nil || n < 0 {
+			return fmt.Errorf("tail must be a number or 'all'")
+		}
+	}
+	if o.Since != "" {
+		_, rfcErr := time.Parse(time.RFC3339, o.Since)
+		_, unixErr := strconv.ParseInt(o.Since, 10, 64)
+		if rfcErr != nil && unixErr != nil && !logSincePattern.MatchString(o.Since) {
+			return fmt.Errorf("since must be an RFC3339 time, a unix timestamp or a duration like 10m")
+		}
+	}
+	return nil
+}
+
+func logsCommand(app models.App, opts LogOptions) string {
+	args := " --no-color --tail=" + opts.Tail
+	if opts.Since != "" {
+		args += " --since=" + opts.Since
+	}
+	if opts.Timestamps {
+		args += " --timestamps"
+	}
+	if opts.Follow {
+		args += " --follow"
+	}
+	if len(opts.Services) > 0 {
+		args += " " + strings.Join(opts.Services, " ")
+	}
+	return fmt.Sprintf("cd %s && docker-compose logs%s 2>&1", app.ComposePath, args)
+}
+
+// AppLogs returns the container logs of an app
+func AppLogs(server models.Server, app models.App, opts LogOptions) (string, error) {
+	opts.Follow = false
+	out, err := runSSH(server, logsCommand(app, opts))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// FollowAppLogs streams the container logs of an app line by line until ctx is done
+func FollowAppLogs(ctx context.Context, server models.Server, app models.App, opts LogOptions, onLine func(line string)) error {
+	opts.Follow = true
+	start := time.Now()
+	err := utils.StreamSSHCommand(ctx, server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey, logsCommand(app, opts), onLine)
+	recordSSHCommand(server, start, err)
+	return err
+}
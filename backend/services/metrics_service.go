@@ -0,0 +1,276 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Downsampling tiers: raw samples are rolled up into 5 minute buckets, which
+// are in turn rolled up into hourly buckets before being deleted.
+const (
+	resolutionRaw    = 0
+	resolutionRollup = 300
+	resolutionHourly = 3600
+)
+
+// Metric names recorded for servers
+var ServerMetricNames = []string{
+	"cpu_usage", "load_avg_1", "load_avg_5", "load_avg_15",
+	"memory_total", "memory_available", "disk_total", "disk_free", "running_containers",
+}
+
+// Metric names recorded for apps
+var AppMetricNames = []string{
+	"cpu_usage", "memory_usage", "net_rx_bytes", "net_tx_bytes", "containers",
+}
+
+// MetricPoint is one bucket of a queried series
+type MetricPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+}
+
+// MetricSeries is a named list of points
+type MetricSeries struct {
+	Metric string        `json:"metric"`
+	Points []MetricPoint `json:"points"`
+}
+
+type metricsRetention struct {
+	raw    time.Duration
+	rollup time.Duration
+	hourly time.Duration
+}
+
+func getMetricsRetention() metricsRetention {
+	return metricsRetention{
+		raw:    time.Duration(envInt("METRICS_RAW_RETENTION_HOURS", 24)) * time.Hour,
+		rollup: time.Duration(envInt("METRICS_ROLLUP_RETENTION_DAYS", 7)) * 24 * time.Hour,
+		hourly: time.Duration(envInt("METRICS_RETENTION_DAYS", 90)) * 24 * time.Hour,
+	}
+}
+
+// envInt reads a positive integer setting from the environment
+func envInt(name string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// RecordSamples stores raw samples for a resource taken at the same instant
+func RecordSamples(db *gorm.DB, resourceType, resourceID string, at time.Time, values map[string]float64) error {
+	samples := make([]models.MetricSample, 0, len(values))
+	for metric, value := range values {
+		samples = append(samples, models.MetricSample{
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			Metric:       metric,
+			Resolution:   resolutionRaw,
+			SampledAt:    at,
+			Value:        value,
+			Min:          value,
+			Max:          value,
+			Count:        1,
+		})
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	return db.Create(&samples).Error
+}
+
+// RecordServerSamples stores the server's current host metrics as raw samples
+func RecordServerSamples(db *gorm.DB, server models.Server) error {
+	if server.Status != "online" || server.Metrics.CollectedAt == nil {
+		return nil
+	}
+	// A snapshot older than the last check is left over from an earlier
+	// collection and was already recorded
+	if server.LastChecked != nil && *server.Metrics.CollectedAt < *server.LastChecked {
+		return nil
+	}
+	m := server.Metrics
+	return RecordSamples(db, "server", server.ID, time.Unix(*m.CollectedAt, 0), map[string]float64{
+		"cpu_usage":          m.CPUUsage,
+		"load_avg_1":         m.LoadAvg1,
+		"load_avg_5":         m.LoadAvg5,
+		"load_avg_15":        m.LoadAvg15,
+		"memory_total":       float64(m.MemTotal),
+		"memory_available":   float64(m.MemAvailable),
+		"disk_total":         float64(m.DiskTotal),
+		"disk_free":          float64(m.DiskFree),
+		"running_containers": float64(server.RunningAppsCount),
+	})
+}
+
+// RecordAppSamples stores a docker stats snapshot of an app as raw samples
+func RecordAppSamples(db *gorm.DB, app models.App, stats AppStats, at time.Time) error {
+	return RecordSamples(db, "app", app.ID, at, map[string]float64{
+		"cpu_usage":    stats.CPUUsage,
+		"memory_usage": float64(stats.MemoryUsage),
+		"net_rx_bytes": float64(stats.NetRxBytes),
+		"net_tx_bytes": float64(stats.NetTxBytes),
+		"containers":   float64(stats.Containers),
+	})
+}
+
+// StartMetricsCollector polls servers and running apps in the background and
+// periodically downsamples and prunes stored samples
+func StartMetricsCollector(db *gorm.DB) {
+	interval := time.Duration(envInt("METRICS_INTERVAL_SECONDS", 60)) * time.Second
+	log.Printf("Metrics collector running every %s", interval)
+
+	go func() {
+		collect := time.NewTicker(interval)
+		compact := time.NewTicker(time.Hour)
+		defer collect.Stop()
+		defer compact.Stop()
+
+		for {
+			select {
+			case <-collect.C:
+				collectMetrics(db)
+			case <-compact.C:
+				if err := CompactMetrics(db, time.Now()); err != nil {
+					log.Printf("Metrics compaction failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func collectMetrics(db *gorm.DB) {
+	var servers []models.Server
+	if err := db.Find(&servers).Error; err != nil {
+		log.Printf("Metrics collector could not load servers: %v", err)
+		return
+	}
+
+	online := make(map[string]models.Server)
+	for i := range servers {
+		if err := RefreshServer(db, &servers[i]); err != nil {
+			log.Printf("Metrics collector could not save server %s: %v", servers[i].Name, err)
+		}
+		if servers[i].Status == "online" {
+			online[servers[i].ID] = servers[i]
+		}
+	}
+
+	var apps []models.App
+	db.Where("status = ?", "running").Find(&apps)
+	for _, app := range apps {
+		server, ok := online[app.ServerID]
+		if !ok {
+			continue
+		}
+		stats, err := CollectAppStats(server, app.ComposePath)
+		if err != nil {
+			log.Printf("Metrics collector could not read stats for app %s: %v", app.Name, err)
+			continue
+		}
+		now := time.Now()
+		if err := RecordAppSamples(db, app, stats, now); err != nil {
+			log.Printf("Metrics collector could not save stats for app %s: %v", app.Name, err)
+		}
+		UpdateAppActivity(db, app, stats, now)
+	}
+}
+
+// CompactMetrics rolls expired samples up into the next tier and deletes
+// hourly buckets past the retention period
+func CompactMetrics(db *gorm.DB, now time.Time) error {
+	retention := getMetricsRetention()
+
+	if err := rollupMetrics(db, resolutionRaw, resolutionRollup, now.Add(-retention.raw)); err != nil {
+		return fmt.Errorf("raw rollup: %w", err)
+	}
+	if err := rollupMetrics(db, resolutionRollup, resolutionHourly, now.Add(-retention.rollup)); err != nil {
+		return fmt.Errorf("5m rollup: %w", err)
+	}
+	return db.Where("resolution = ? AND sampled_at < ?", resolutionHourly, now.Add(-retention.hourly)).
+		Delete(&models.MetricSample{}).Error
+}
+
+// rollupMetrics aggregates complete buckets older than cutoff from one
+// resolution into a coarser one and removes the source rows
+func rollupMetrics(db *gorm.DB, from, to int, cutoff time.Time) error {
+	// Only roll up whole target buckets so a bucket is never written twice
+	cutoff = time.Unix(cutoff.Unix()/int64(to)*int64(to), 0)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO metric_samples (resource_type, resource_id, metric, resolution, sampled_at, value, min, max, count)
+			SELECT resource_type, resource_id, metric, ?,
+				to_timestamp(floor(extract(epoch FROM sampled_at) / ?) * ?),
+				sum(value * count) / sum(count), min(min), max(max), sum(count)
+			FROM metric_samples
+			WHERE resolution = ? AND sampled_at < ?
+			GROUP BY resource_type, resource_id, metric, 5`,
+			to, to, to, from, cutoff).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("resolution = ? AND sampled_at < ?", from, cutoff).Delete(&models.MetricSample{}).Error
+	})
+}
+
+// MinimumStep returns the finest step that stored data supports for a range starting at from
+func MinimumStep(from, now time.Time) int {
+	retention := getMetricsRetention()
+	switch {
+	case from.After(now.Add(-retention.raw)):
+		return 1
+	case from.After(now.Add(-retention.rollup)):
+		return resolutionRollup
+	default:
+		return resolutionHourly
+	}
+}
+
+// QueryMetricSeries returns step-second buckets for each metric of a resource in [from, to]
+func QueryMetricSeries(db *gorm.DB, resourceType, resourceID string, metrics []string, from, to time.Time, step int) ([]MetricSeries, error) {
+	type row struct {
+		Metric string
+		Bucket int64
+		Value  float64
+		Min    float64
+		Max    float64
+	}
+	var rows []row
+	err := db.Raw(`
+		SELECT metric,
+			floor(extract(epoch FROM sampled_at) / ?)::bigint * ? AS bucket,
+			sum(value * count) / sum(count) AS value, min(min) AS min, max(max) AS max
+		FROM metric_samples
+		WHERE resource_type = ? AND resource_id = ? AND metric IN ? AND sampled_at BETWEEN ? AND ?
+		GROUP BY metric, bucket
+		ORDER BY metric, bucket`,
+		step, step, resourceType, resourceID, metrics, from, to).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byMetric := make(map[string][]MetricPoint, len(metrics))
+	for _, r := range rows {
+		byMetric[r.Metric] = append(byMetric[r.Metric], MetricPoint{Timestamp: r.Bucket, Value: r.Value, Min: r.Min, Max: r.Max})
+	}
+
+	series := make([]MetricSeries, 0, len(metrics))
+	for _, metric := range metrics {
+		points := byMetric[metric]
+		if points == nil {
+			points = []MetricPoint{}
+		}
+		series = append(series, MetricSeries{Metric: metric, Points: points})
+	}
+	return series, nil
+}
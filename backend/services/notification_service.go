@@ -0,0 +1,192 @@
+package services
+
+import (
+	"backend/models"
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification kinds; except for the test email each matches a notification_preferences column
+const (
+	notificationAppExpiring   = "app_expiring"
+	notificationStartFailed   = "start_failed"
+	notificationServerOffline = "server_offline"
+	notificationAppIdle       = "app_idle" // Sent to users who opted in to app_expiring
+	notificationTest          = "test"
+)
+
+// preferenceColumn returns the notification_preferences column that opts in to a kind
+func preferenceColumn(kind string) string {
+	if kind == notificationAppIdle {
+		return notificationAppExpiring
+	}
+	return kind
+}
+
+// notificationTemplates holds the subject and body template of each kind.
+// The first line is the subject, the rest is the body.
+var notificationTemplates = map[string]*template.Template{
+	notificationAppExpiring: template.Must(template.New(notificationAppExpiring).Parse(
+		`[WebManager] {{.App.Name}} stops in {{.Minutes}} minutes
+Hi {{.Name}},
+
+{{.App.Name}} ({{.App.Domain}}) is scheduled to auto-stop at {{.StopsAt}}.
+If you are still using it, extend the run time from the dashboard{{if .DashboardURL}}: {{.DashboardURL}}{{end}}.
+`)),
+	notificationStartFailed: template.Must(template.New(notificationStartFailed).Parse(
+		`[WebManager] Failed to start {{.App.Name}}
+Hi {{.Name}},
+
+Starting {{.App.Name}} ({{.App.Domain}}) failed:
+
+{{.Error}}
+{{if .DashboardURL}}
+Dashboard: {{.DashboardURL}}
+{{end}}`)),
+	notificationServerOffline: template.Must(template.New(notificationServerOffline).Parse(
+		`[WebManager] Server {{.Server.Name}} is offline
+Hi {{.Name}},
+
+Server {{.Server.Name}} ({{.Server.Address}}) stopped responding at {{.CheckedAt}}.
+Apps on this server cannot be started or stopped until it is back online.
+`)),
+	notificationAppIdle: template.Must(template.New(notificationAppIdle).Parse(
+		`[WebManager] {{.App.Name}} is idle and stops in {{.Minutes}} minutes
+Hi {{.Name}},
+
+{{.App.Name}} ({{.App.Domain}}) has had no traffic or CPU activity for a while and will be stopped at {{.StopsAt}}.
+Any activity before then keeps it running{{if .DashboardURL}}; manage it from the dashboard: {{.DashboardURL}}{{end}}.
+`)),
+	notificationTest: template.Must(template.New(notificationTest).Parse(
+		`[WebManager] Test notification
+Hi {{.Name}},
+
+This is a test email from WebManager. SMTP delivery is working.
+`)),
+}
+
+// notificationData is the template context of a notification
+type notificationData struct {
+	Name         string
+	App          models.App
+	Server       models.Server
+	Minutes      int
+	StopsAt      string
+	CheckedAt    string
+	Error        string
+	DashboardURL string
+}
+
+// renderNotification returns the subject and body of a notification
+func renderNotification(kind string, data notificationData) (string, string, error) {
+	data.DashboardURL = os.Getenv("DASHBOARD_URL")
+	var buf bytes.Buffer
+	if err := notificationTemplates[kind].Execute(&buf, data); err != nil {
+		return "", "", err
+	}
+	subject, body, _ := strings.Cut(buf.String(), "\n")
+	return subject, body, nil
+}
+
+// sendNotification renders and sends a notification to one user
+func sendNotification(user models.User, kind string, data notificationData) error {
+	data.Name = user.FullName
+	if data.Name == "" {
+		data.Name = user.Username
+	}
+	subject, body, err := renderNotification(kind, data)
+	if err != nil {
+		return err
+	}
+	return SendMail([]string{user.Email}, subject, body)
+}
+
+// notifyUsers sends a notification in the background to each user who opted in to its kind
+func notifyUsers(db *gorm.DB, userIDs []string, kind string, data notificationData) {
+	if !MailEnabled() {
+		return
+	}
+
+	query := db.Model(&models.User{}).
+		Joins("JOIN notification_preferences np ON np.user_id = users.id").
+		Where("np."+preferenceColumn(kind)+" = ?", true).
+		Where("users.is_active = ? AND users.email <> ''", true)
+	if userIDs != nil {
+		query = query.Where("users.id IN ?", userIDs)
+	}
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil {
+		log.Printf("Could not load recipients for %s notification: %v", kind, err)
+		return
+	}
+
+	for _, user := range users {
+		user := user
+		err := EnqueueJob("email "+kind+" to "+user.Username, func() {
+			if err := sendNotification(user, kind, data); err != nil {
+				log.Printf("Failed to email %s notification to %s: %v", kind, user.Email, err)
+			}
+		})
+		if err != nil {
+			log.Printf("Email to %s not queued: %v", user.Email, err)
+		}
+	}
+}
+
+// NotifyAppExpiring warns the user who started an app that it will auto-stop soon
+func NotifyAppExpiring(db *gorm.DB, app models.App, minutes int) {
+	if app.StartedByID == nil || app.TimerEndsAt == nil {
+		return
+	}
+	notifyUsers(db, []string{*app.StartedByID}, notificationAppExpiring, notificationData{
+		App:     app,
+		Minutes: minutes,
+		StopsAt: time.Unix(*app.TimerEndsAt, 0).Format(time.RFC1123),
+	})
+}
+
+// NotifyAppIdle warns the user who started an app that it will be stopped for inactivity
+func NotifyAppIdle(db *gorm.DB, app models.App, minutes int, stopsAt time.Time) {
+	if app.StartedByID == nil {
+		return
+	}
+	notifyUsers(db, []string{*app.StartedByID}, notificationAppIdle, notificationData{
+		App:     app,
+		Minutes: minutes,
+		StopsAt: stopsAt.Format(time.RFC1123),
+	})
+}
+
+// NotifyStartFailed tells the user who tried to start an app that it failed
+func NotifyStartFailed(db *gorm.DB, app models.App, userID string, startErr error) {
+	if userID == "" {
+		return
+	}
+	notifyUsers(db, []string{userID}, notificationStartFailed, notificationData{
+		App:   app,
+		Error: startErr.Error(),
+	})
+}
+
+// NotifyServerOffline tells every opted-in user that a server went offline
+func NotifyServerOffline(db *gorm.DB, server models.Server) {
+	checkedAt := time.Now()
+	if server.LastChecked != nil {
+		checkedAt = time.Unix(*server.LastChecked, 0)
+	}
+	notifyUsers(db, nil, notificationServerOffline, notificationData{
+		Server:    server,
+		CheckedAt: checkedAt.Format(time.RFC1123),
+	})
+}
+
+// SendTestNotification emails a test message to a user
+func SendTestNotification(user models.User) error {
+	return sendNotification(user, notificationTest, notificationData{})
+}
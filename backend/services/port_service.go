@@ -0,0 +1,204 @@
+package services
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+	"gorm.io/gorm"
+)
+
+// ErrPortConflict is returned when an app publishes a host port that is already taken
+var ErrPortConflict = errors.New("port conflict")
+
+// composeConfig is the part of `docker-compose config` output needed to find published ports
+type composeConfig struct {
+	Services map[string]struct {
+		Ports []interface{} `yaml:"ports"`
+	} `yaml:"services"`
+}
+
+// ComposePublishedPorts returns the host ports an app's compose configuration publishes
+func ComposePublishedPorts(server models.Server, app models.App) ([]models.PublishedPort, error) {
+	out, err := runSSH(server, fmt.Sprintf("cd %s && docker-compose%s config 2>&1", app.ComposePath, composeFileArgs(app)))
+	if err != nil {
+		return nil, fmt.Errorf("could not read compose configuration: %w: %s", err, strings.TrimSpace(out))
+	}
+	return parsePublishedPorts([]byte(out))
+}
+
+func parsePublishedPorts(config []byte) ([]models.PublishedPort, error) {
+	var parsed composeConfig
+	if err := yaml.Unmarshal(config, &parsed); err != nil {
+		return nil, fmt.Errorf("could not parse compose configuration: %w", err)
+	}
+
+	ports := []models.PublishedPort{}
+	for service, definition := range parsed.Services {
+		for _, entry := range definition.Ports {
+			var hostIP, published, protocol string
+			switch port := entry.(type) {
+			case string:
+				hostIP, published, protocol = parseShortPort(port)
+			case int:
+				continue // Container port only, the host port is random
+			case map[interface{}]interface{}:
+				hostIP = fmt.Sprint(valueOr(port["host_ip"], ""))
+				published = fmt.Sprint(valueOr(port["published"], ""))
+				protocol = fmt.Sprint(valueOr(port["protocol"], "tcp"))
+			}
+
+			first, last, ok := parsePortRange(published)
+			if !ok {
+				continue
+			}
+			for p := first; p <= last; p++ {
+				ports = append(ports, models.PublishedPort{Service: service, HostIP: hostIP, Port: p, Protocol: protocol})
+			}
+		}
+	}
+	return ports, nil
+}
+
+func valueOr(value interface{}, fallback string) interface{} {
+	if value == nil {
+		return fallback
+	}
+	return value
+}
+
+// parseShortPort splits "[ip:]published:target[/protocol]"
+func parseShortPort(spec string) (hostIP, published, protocol string) {
+	spec, protocol, hasProtocol := strings.Cut(spec, "/")
+	if !hasProtocol {
+		protocol = "tcp"
+	}
+
+	// IPv6 host addresses are bracketed: [::1]:8080:80
+	if strings.HasPrefix(spec, "[") {
+		if end := strings.Index(spec, "]:"); end > 0 {
+			hostIP = spec[1:end]
+			spec = spec[end+2:]
+			published, _, _ = strings.Cut(spec, ":")
+			return
+		}
+	}
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 2:
+		published = parts[0]
+	case 3:
+		hostIP, published = parts[0], parts[1]
+	}
+	return
+}
+
+// parsePortRange parses "8080" or "8000-8005"
+func parsePortRange(published string) (int, int, bool) {
+	if published == "" {
+		return 0, 0, false
+	}
+	from, to, isRange := strings.Cut(published, "-")
+	first, err := strconv.Atoi(from)
+	if err != nil || first <= 0 {
+		return 0, 0, false
+	}
+	last := first
+	if isRange {
+		if last, err = strconv.Atoi(to); err != nil || last < first {
+			return 0, 0, false
+		}
+	}
+	return first, last, true
+}
+
+// hostIPsOverlap reports whether two bind addresses can clash; empty and wildcard addresses match everything
+func hostIPsOverlap(a, b string) bool {
+	wildcard := func(ip string) bool { return ip == "" || ip == "0.0.0.0" || ip == "::" || ip == "*" }
+	return wildcard(a) || wildcard(b) || a == b
+}
+
+func portsClash(a, b models.PublishedPort) bool {
+	return a.Port == b.Port && a.Protocol == b.Protocol && hostIPsOverlap(a.HostIP, b.HostIP)
+}
+
+// listeningPorts returns the TCP and UDP sockets listening on a server
+func listeningPorts(server models.Server) ([]models.PublishedPort, error) {
+	out, err := runSSH(server, "ss -Hltun")
+	if err != nil {
+		return nil, err
+	}
+	return parseListeningPorts(out), nil
+}
+
+// parseListeningPorts reads `ss -Hltun` output
+func parseListeningPorts(out string) []models.PublishedPort {
+	var ports []models.PublishedPort
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		// Netid State Recv-Q Send-Q Local:Port Peer:Port
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		local := fields[4]
+		sep := strings.LastIndex(local, ":")
+		if sep < 0 {
+			continue
+		}
+		port, err := strconv.Atoi(local[sep+1:])
+		if err != nil {
+			continue
+		}
+		ip := strings.Trim(local[:sep], "[]")
+		if zone := strings.Index(ip, "%"); zone >= 0 {
+			ip = ip[:zone]
+		}
+		ports = append(ports, models.PublishedPort{HostIP: ip, Port: port, Protocol: fields[0]})
+	}
+	return ports
+}
+
+// CheckPortConflicts returns the ports an app publishes, or an ErrPortConflict
+// naming the running app or host socket already using one of them
+func CheckPortConflicts(db *gorm.DB, server models.Server, app models.App) ([]models.PublishedPort, error) {
+	ports, err := ComposePublishedPorts(server, app)
+	if err != nil || len(ports) == 0 {
+		return ports, err
+	}
+
+	var others []models.App
+	db.Where("server_id = ? AND status = ? AND id <> ?", app.ServerID, "running", app.ID).Find(&others)
+	for _, other := range others {
+		for _, theirs := range other.PublishedPorts {
+			for _, ours := range ports {
+				if portsClash(ours, theirs) {
+					return nil, fmt.Errorf("%w: port %d/%s of service %s is already published by %s (service %s)",
+						ErrPortConflict, ours.Port, ours.Protocol, ours.Service, other.Name, theirs.Service)
+				}
+			}
+		}
+	}
+
+	// A running app is being restarted and listens on its own ports
+	if app.Status == "running" {
+		return ports, nil
+	}
+	listening, err := listeningPorts(server)
+	if err != nil {
+		log.Printf("Could not list listening sockets on %s, skipping host port check: %v", server.Name, err)
+		return ports, nil
+	}
+	for _, ours := range ports {
+		for _, socket := range listening {
+			if portsClash(ours, socket) {
+				return nil, fmt.Errorf("%w: port %d/%s of service %s is already in use on %s (listening on %s)",
+					ErrPortConflict, ours.Port, ours.Protocol, ours.Service, server.Name, socket.HostIP)
+			}
+		}
+	}
+	return ports, nil
+}
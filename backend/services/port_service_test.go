@@ -0,0 +1,134 @@
+package services
+
+import (
+	"backend/models"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseShortPort(t *testing.T) {
+	tests := []struct {
+		spec                        string
+		hostIP, published, protocol string
+	}{
+		{"80", "", "", "tcp"},
+		{"8080:80", "", "8080", "tcp"},
+		{"8080:80/udp", "", "8080", "udp"},
+		{"127.0.0.1:8080:80", "127.0.0.1", "8080", "tcp"},
+		{"127.0.0.1::80", "127.0.0.1", "", "tcp"},
+		{"8000-8005:80-85", "", "8000-8005", "tcp"},
+		{"[::1]:8080:80", "::1", "8080", "tcp"},
+		{"[::1]:8080:80/udp", "::1", "8080", "udp"},
+	}
+	for _, tt := range tests {
+		hostIP, published, protocol := parseShortPort(tt.spec)
+		if hostIP != tt.hostIP || published != tt.published || protocol != tt.protocol {
+			t.Errorf("parseShortPort(%q) = %q, %q, %q; want %q, %q, %q",
+				tt.spec, hostIP, published, protocol, tt.hostIP, tt.published, tt.protocol)
+		}
+	}
+}
+
+func TestParsePublishedPorts(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []models.PublishedPort
+	}{
+		{
+			name:   "no services",
+			config: "name: demo\n",
+			want:   []models.PublishedPort{},
+		},
+		{
+			name: "short syntax",
+			config: `services:
+  web:
+    ports:
+      - "8080:80"
+      - "127.0.0.1:5353:53/udp"
+      - "9000"
+`,
+			want: []models.PublishedPort{
+				{Service: "web", Port: 8080, Protocol: "tcp"},
+				{Service: "web", HostIP: "127.0.0.1", Port: 5353, Protocol: "udp"},
+			},
+		},
+		{
+			name: "long syntax as printed by docker-compose config",
+			config: `services:
+  db:
+    ports:
+      - mode: ingress
+        target: 5432
+        published: "5432"
+        protocol: tcp
+      - target: 6000
+        host_ip: 10.0.0.1
+        published: 6000
+      - target: 7000
+`,
+			want: []models.PublishedPort{
+				{Service: "db", Port: 5432, Protocol: "tcp"},
+				{Service: "db", HostIP: "10.0.0.1", Port: 6000, Protocol: "tcp"},
+			},
+		},
+		{
+			name: "ranges and several services",
+			config: `services:
+  api:
+    ports:
+      - "3000-3002:3000-3002"
+  worker:
+    ports:
+      - 9100
+      - "bad:80"
+`,
+			want: []models.PublishedPort{
+				{Service: "api", Port: 3000, Protocol: "tcp"},
+				{Service: "api", Port: 3001, Protocol: "tcp"},
+				{Service: "api", Port: 3002, Protocol: "tcp"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePublishedPorts([]byte(tt.config))
+			if err != nil {
+				t.Fatalf("parsePublishedPorts: %v", err)
+			}
+			sort.SliceStable(got, func(i, j int) bool { return got[i].Service < got[j].Service })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePublishedPorts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parsePublishedPorts([]byte("services: [")); err == nil {
+		t.Error("parsePublishedPorts accepted invalid YAML")
+	}
+}
+
+func TestParseListeningPorts(t *testing.T) {
+	out := `tcp   LISTEN 0      4096         0.0.0.0:22         0.0.0.0:*
+tcp   LISTEN 0      511        127.0.0.1:6379       0.0.0.0:*
+tcp   LISTEN 0      4096            [::]:8080          [::]:*
+udp   UNCONN 0      0      127.0.0.53%lo:53         0.0.0.0:*
+udp   UNCONN 0      0     [fe80::1%eth0]:546          [::]:*
+tcp   LISTEN 0      128                *:9100             *:*
+garbage
+tcp   LISTEN 0      128     127.0.0.1:notaport     0.0.0.0:*
+`
+	want := []models.PublishedPort{
+		{HostIP: "0.0.0.0", Port: 22, Protocol: "tcp"},
+		{HostIP: "127.0.0.1", Port: 6379, Protocol: "tcp"},
+		{HostIP: "::", Port: 8080, Protocol: "tcp"},
+		{HostIP: "127.0.0.53", Port: 53, Protocol: "udp"},
+		{HostIP: "fe80::1", Port: 546, Protocol: "udp"},
+		{HostIP: "*", Port: 9100, Protocol: "tcp"},
+	}
+	if got := parseListeningPorts(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseListeningPorts() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"backend/models"
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v2"
+	"gorm.io/gorm"
+)
+
+// domainPattern keeps generated proxy configs safe from odd Domain values
+var domainPattern = regexp.MustCompile(`^(\*\.)?([A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)*[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// pendingProxySyncs holds servers with a queued proxy sync so bursts of starts and stops sync once
+var pendingProxySyncs sync.Map
+
+// sleepingPage is shown for the domain of a stopped app
+var sleepingPage = template.Must(template.New("sleeping").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Name}} is sleeping</title>
+<style>body{font-family:sans-serif;text-align:center;padding-top:15vh;color:#333}</style></head>
+<body>
+<h1>{{.Name}} is sleeping</h1>
+<p>This environment is stopped to save resources.</p>
+{{if .DashboardURL}}<p><a href="{{.DashboardURL}}">Start it from WebManager</a></p>{{else}}<p>Start it from WebManager.</p>{{end}}
+</body>
+</html>
+`))
+
+// RenderSleepingPage returns the "environment sleeping" page of an app
+func RenderSleepingPage(app models.App) string {
+	var buf bytes.Buffer
+	sleepingPage.Execute(&buf, map[string]string{"Name": app.Name, "DashboardURL": os.Getenv("DASHBOARD_URL")})
+	return buf.String()
+}
+
+// ProxyPort returns the host port the proxy routes an app's domain to, or 0 if there is none
+func ProxyPort(app models.App) int {
+	if app.ProxyPort > 0 {
+		return app.ProxyPort
+	}
+	for _, port := range app.PublishedPorts {
+		if port.Protocol == "tcp" {
+			return port.Port
+		}
+	}
+	return 0
+}
+
+func proxyUpstreamHost(server models.Server) string {
+	if server.ProxyUpstreamHost != "" {
+		return server.ProxyUpstreamHost
+	}
+	return "127.0.0.1"
+}
+
+// ProxyConfigPath returns where the generated config of a server is written
+func ProxyConfigPath(server models.Server) string {
+	if server.ProxyConfigPath != "" {
+		return server.ProxyConfigPath
+	}
+	if server.ProxyType == "traefik" {
+		return "/etc/traefik/dynamic/webmanager.yml"
+	}
+	return "/etc/nginx/conf.d/webmanager.conf"
+}
+
+func proxyReloadCmd(server models.Server) string {
+	if server.ProxyReloadCmd != "" || server.ProxyType != "nginx" {
+		// Traefik's file provider picks up changes by itself
+		return server.ProxyReloadCmd
+	}
+	return "nginx -t && nginx -s reload"
+}
+
+// proxiedApps returns the apps of a server that have a usable domain, sorted by domain
+func proxiedApps(db *gorm.DB, server models.Server) []models.App {
+	var apps []models.App
+	db.Where("server_id = ?", server.ID).Find(&apps)
+	valid := apps[:0]
+	for _, app := range apps {
+		if domainPattern.MatchString(app.Domain) {
+			valid = append(valid, app)
+		} else if app.Domain != "" {
+			log.Printf("Proxy config for %s skips app %s: invalid domain %q", server.Name, app.Name, app.Domain)
+		}
+	}
+	sort.Slice(valid, func(i, j int) bool { return valid[i].Domain < valid[j].Domain })
+	return valid
+}
+
+// RenderNginxConfig returns server blocks proxying running apps and serving
+// the sleeping page for stopped ones
+func RenderNginxConfig(server models.Server, apps []models.App) string {
+	var b strings.Builder
+	b.WriteString("# Generated by WebManager, do not edit: changes are overwritten when apps start or stop\n")
+	for _, app := range apps {
+		fmt.Fprintf(&b, "\n# %s (%s)\nserver {\n    listen 80;\n    server_name %s;\n", app.Name, app.Status, app.Domain)
+		if port := ProxyPort(app); app.Status == "running" && port > 0 {
+			fmt.Fprintf(&b, `
+    location / {
+        proxy_pass http://%s:%d;
+        proxy_http_version 1.1;
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection $http_connection;
+    }
+}
+`, proxyUpstreamHost(server), port)
+			continue
+		}
+		// nginx interpolates $variables and needs quotes escaped in the inline page
+		page := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", "&#36;", "\n", " ").Replace(RenderSleepingPage(app))
+		fmt.Fprintf(&b, `
+    location / {
+        default_type text/html;
+        add_header Retry-After 60 always;
+        return 503 "%s";
+    }
+}
+`, page)
+	}
+	return b.String()
+}
+
+// RenderTraefikConfig returns a Traefik file-provider configuration routing
+// running apps, and stopped apps to the sleeping page if SLEEPING_PAGE_URL is set
+func RenderTraefikConfig(server models.Server, apps []models.App) (string, error) {
+	routers := map[string]interface{}{}
+	services := map[string]interface{}{}
+	middlewares := map[string]interface{}{}
+	sleepingURL := os.Getenv("SLEEPING_PAGE_URL")
+
+	for _, app := range apps {
+		name := "webmanager-" + app.ID
+		rule := fmt.Sprintf("Host(`%s`)", app.Domain)
+		if port := ProxyPort(app); app.Status == "running" && port > 0 {
+			routers[name] = map[string]interface{}{"rule": rule, "service": name}
+			services[name] = map[string]interface{}{
+				"loadBalancer": map[string]interface{}{
+					"servers": []map[string]string{{"url": fmt.Sprintf("http://%s:%d", proxyUpstreamHost(server), port)}},
+				},
+			}
+		} else if sleepingURL != "" {
+			routers[name] = map[string]interface{}{
+				"rule":        rule,
+				"service":     "webmanager-sleeping",
+				"middlewares": []string{"webmanager-sleeping"},
+			}
+		}
+	}
+	if sleepingURL != "" {
+		services["webmanager-sleeping"] = map[string]interface{}{
+			"loadBalancer": map[string]interface{}{"servers": []map[string]string{{"url": sleepingURL}}},
+		}
+		middlewares["webmanager-sleeping"] = map[string]interface{}{"replacePath": map[string]string{"path": "/sleeping"}}
+	}
+
+	httpConfig := map[string]interface{}{"routers": routers, "services": services}
+	if len(middlewares) > 0 {
+		httpConfig["middlewares"] = middlewares
+	}
+	out, err := yaml.Marshal(map[string]interface{}{"http": httpConfig})
+	if err != nil {
+		return "", err
+	}
+	return "# Generated by WebManager, do not edit: changes are overwritten when apps start or stop\n" + string(out), nil
+}
+
+// RenderProxyConfig returns the proxy configuration of a server's apps
+func RenderProxyConfig(db *gorm.DB, server models.Server) (string, error) {
+	apps := proxiedApps(db, server)
+	switch server.ProxyType {
+	case "nginx":
+		return RenderNginxConfig(server, apps), nil
+	case "traefik":
+		return RenderTraefikConfig(server, apps)
+	default:
+		return "", fmt.Errorf("server %s has no proxy type configured", server.Name)
+	}
+}
+
+// SyncProxyConfig writes the proxy configuration to a server and reloads the
+// proxy. If validation or the reload fails the previous file is restored.
+func SyncProxyConfig(db *gorm.DB, server models.Server) (string, error) {
+	config, err := RenderProxyConfig(db, server)
+	if err != nil {
+		return "", err
+	}
+
+	path := ProxyConfigPath(server)
+	script := fmt.Sprintf(`f=%q
+if [ -f "$f" ]; then cp "$f" "$f.bak"; fi
+cat > "$f.tmp" && mv "$f.tmp" "$f" || exit 1`, path)
+	if reload := proxyReloadCmd(server); reload != "" {
+		script += fmt.Sprintf(`
+if ! ( %s ) 2>&1; then
+  if [ -f "$f.bak" ]; then mv "$f.bak" "$f"; else rm -f "$f"; fi
+  exit 1
+fi`, reload)
+	}
+
+	out, err := runSSHWithInput(server, "sh -c "+shellQuote(script), config)
+	if err != nil {
+		return out, fmt.Errorf("proxy config rejected, previous config restored: %w: %s", err, strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// shellQuote single-quotes s for the remote shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// QueueProxySync syncs the proxy configuration of a server in the background
+func QueueProxySync(db *gorm.DB, server models.Server) {
+	if server.ProxyType == "" {
+		return
+	}
+	if _, queued := pendingProxySyncs.LoadOrStore(server.ID, true); queued {
+		return
+	}
+	err := EnqueueJob("proxy sync "+server.Name, func() {
+		// Changes made while this sync runs queue another one
+		pendingProxySyncs.Delete(server.ID)
+		var current models.Server
+		if err := db.First(&current, "id = ?", server.ID).Error; err != nil {
+			return
+		}
+		if _, err := SyncProxyConfig(db, current); err != nil {
+			log.Printf("Proxy sync of %s failed: %v", server.Name, err)
+		}
+	})
+	if err != nil {
+		pendingProxySyncs.Delete(server.ID)
+		log.Printf("Proxy sync of %s not queued: %v", server.Name, err)
+	}
+}
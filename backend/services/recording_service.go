@@ -0,0 +1,207 @@
+package services
+
+import (
+	"backend/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// RecordingsDir is where session recordings are stored (RECORDINGS_DIR)
+func RecordingsDir() string {
+	if dir := os.Getenv("RECORDINGS_DIR"); dir != "" {
+		return dir
+	}
+	return "recordings"
+}
+
+// Recorder writes a terminal session as an asciicast v2 file: a JSON header
+// line followed by one [seconds, type, data] event per line
+type Recorder struct {
+	db        *gorm.DB
+	recording models.SessionRecording
+	file      *os.File
+	maxBytes  int64
+	mu        sync.Mutex
+	pending   map[string][]byte // Incomplete UTF-8 sequences per event type
+}
+
+// StartRecording creates the recording of a terminal session started by the given audit entry
+func StartRecording(db *gorm.DB, auditLog models.AuditLog, title string, cols, rows int) (*Recorder, error) {
+	if err := os.MkdirAll(RecordingsDir(), 0o700); err != nil {
+		return nil, err
+	}
+
+	recording := models.SessionRecording{
+		AuditLogID:   auditLog.ID,
+		ResourceType: auditLog.ResourceType,
+		ResourceID:   auditLog.ResourceID,
+		ResourceName: auditLog.ResourceName,
+		UserID:       auditLog.UserID,
+		Username:     auditLog.Username,
+		Title:        title,
+		StartedAt:    time.Now(),
+	}
+	if err := db.Create(&recording).Error; err != nil {
+		return nil, err
+	}
+	recording.Path = filepath.Join(RecordingsDir(), recording.ID+".cast")
+	db.Model(&recording).Update("path", recording.Path)
+
+	file, err := os.OpenFile(recording.Path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		db.Delete(&recording)
+		return nil, err
+	}
+
+	r := &Recorder{
+		db:        db,
+		recording: recording,
+		file:      file,
+		maxBytes:  int64(envInt("RECORDING_MAX_MB", 50)) << 20,
+		pending:   map[string][]byte{},
+	}
+	header, _ := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"width":     cols,
+		"height":    rows,
+		"timestamp": recording.StartedAt.Unix(),
+		"title":     title,
+		"env":       map[string]string{"TERM": "xterm-256color"},
+	})
+	r.writeLine(header)
+	return r, nil
+}
+
+// ID returns the ID of the recording
+func (r *Recorder) ID() string {
+	return r.recording.ID
+}
+
+// Output records data printed by the remote shell
+func (r *Recorder) Output(data []byte) {
+	r.event("o", data)
+}
+
+// Input records data typed by the user
+func (r *Recorder) Input(data []byte) {
+	r.event("i", data)
+}
+
+// Resize records a terminal size change
+func (r *Recorder) Resize(cols, rows int) {
+	r.event("r", []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+func (r *Recorder) event(kind string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil || r.recording.Truncated {
+		return
+	}
+
+	// Keep a multi-byte character split across reads for the next event
+	data = append(r.pending[kind], data...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending[kind] = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return
+	}
+
+	line, _ := json.Marshal([]interface{}{
+		time.Since(r.recording.StartedAt).Seconds(), kind, string(data[:cut]),
+	})
+	if r.recording.Size+int64(len(line))+1 > r.maxBytes {
+		r.recording.Truncated = true
+		return
+	}
+	r.writeLine(line)
+}
+
+func (r *Recorder) writeLine(line []byte) {
+	n, err := r.file.Write(append(line, '\n'))
+	r.recording.Size += int64(n)
+	if err != nil {
+		log.Printf("Recording %s: write failed: %v", r.recording.ID, err)
+	}
+}
+
+// Close finishes the recording and stores its size and end time
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	r.file.Close()
+	r.file = nil
+
+	now := time.Now()
+	r.db.Model(&r.recording).Updates(map[string]interface{}{
+		"size":      r.recording.Size,
+		"truncated": r.recording.Truncated,
+		"ended_at":  now,
+	})
+}
+
+// StartRecordingCleanup applies the retention limits to recordings every hour:
+// RECORDING_RETENTION_DAYS (default 90) and RECORDING_MAX_TOTAL_MB (default 0, no limit)
+func StartRecordingCleanup(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			CleanupRecordings(db)
+			<-ticker.C
+		}
+	}()
+}
+
+// CleanupRecordings deletes recordings past their retention, oldest first
+func CleanupRecordings(db *gorm.DB) {
+	cutoff := time.Now().AddDate(0, 0, -envInt("RECORDING_RETENTION_DAYS", 90))
+	var expired []models.SessionRecording
+	db.Where("started_at < ?", cutoff).Find(&expired)
+	for _, recording := range expired {
+		deleteRecording(db, recording)
+	}
+
+	maxTotal := int64(envInt("RECORDING_MAX_TOTAL_MB", 0)) << 20
+	if maxTotal <= 0 {
+		return
+	}
+	var total int64
+	db.Model(&models.SessionRecording{}).Select("COALESCE(SUM(size), 0)").Scan(&total)
+	for total > maxTotal {
+		var oldest models.SessionRecording
+		if err := db.Where("ended_at IS NOT NULL").Order("started_at").First(&oldest).Error; err != nil {
+			return
+		}
+		deleteRecording(db, oldest)
+		total -= oldest.Size
+	}
+}
+
+func deleteRecording(db *gorm.DB, recording models.SessionRecording) {
+	if err := os.Remove(recording.Path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Could not delete recording %s: %v", recording.ID, err)
+		return
+	}
+	db.Delete(&recording)
+	log.Printf("Deleted recording %s of %s (%s)", recording.ID, recording.Username, recording.StartedAt.Format(time.RFC3339))
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReservationConflict is returned when a new reservation overlaps an existing one
+var ErrReservationConflict = errors.New("reservation conflicts with an existing reservation")
+
+// CreateReservation stores a reservation unless it overlaps another reservation of the same app.
+// The app row is locked so concurrent bookings cannot both pass the conflict check.
+func CreateReservation(db *gorm.DB, reservation *models.Reservation) (*models.Reservation, error) {
+	var conflict models.Reservation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var app models.App
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&app, "id = ?", reservation.AppID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("app_id = ? AND starts_at < ? AND ends_at > ?", reservation.AppID, reservation.EndsAt, reservation.StartsAt).
+			First(&conflict).Error
+		if err == nil {
+			return ErrReservationConflict
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Create(reservation).Error
+	})
+	if errors.Is(err, ErrReservationConflict) {
+		return &conflict, err
+	}
+	return nil, err
+}
+
+// ActiveReservation returns the reservation of an app covering now, or nil
+func ActiveReservation(db *gorm.DB, appID string, now time.Time) *models.Reservation {
+	var reservation models.Reservation
+	err := db.Where("app_id = ? AND starts_at <= ? AND ends_at > ?", appID, now, now).First(&reservation).Error
+	if err != nil {
+		return nil
+	}
+	return &reservation
+}
+
+// CheckReservationHolder returns an error if the app is reserved right now by someone other than the actor
+func CheckReservationHolder(db *gorm.DB, app models.App, actor Actor, isAdmin bool) error {
+	if isAdmin {
+		return nil
+	}
+	reservation := ActiveReservation(db, app.ID, time.Now())
+	if reservation == nil || reservation.UserID == actor.UserID {
+		return nil
+	}
+	return fmt.Errorf("%s is reserved by %s until %s (%s)",
+		app.Name, reservation.Username, reservation.EndsAt.Format(time.RFC3339), reservation.Reason)
+}
+
+// runDueReservations performs the auto-start and auto-stop of reservation windows
+func runDueReservations(db *gorm.DB, now time.Time) {
+	var starting []models.Reservation
+	db.Where("auto_start = ? AND auto_started = ? AND starts_at <= ? AND ends_at > ?", true, false, now, now).Find(&starting)
+	for _, reservation := range starting {
+		if claimReservation(db, reservation, "auto_started") {
+			reservation := reservation
+			if err := EnqueueJob("reservation start "+reservation.ID, func() { startReservedApp(db, reservation) }); err != nil {
+				releaseReservation(db, reservation, "auto_started")
+				log.Printf("Reservation %s: auto-start not queued: %v", reservation.ID, err)
+			}
+		}
+	}
+
+	var ending []models.Reservation
+	db.Where("auto_stop = ? AND auto_stopped = ? AND ends_at <= ?", true, false, now).Find(&ending)
+	for _, reservation := range ending {
+		if claimReservation(db, reservation, "auto_stopped") {
+			reservation := reservation
+			if err := EnqueueJob("reservation stop "+reservation.ID, func() { stopReservedApp(db, reservation) }); err != nil {
+				releaseReservation(db, reservation, "auto_stopped")
+				log.Printf("Reservation %s: auto-stop not queued: %v", reservation.ID, err)
+			}
+		}
+	}
+}
+
+// claimReservation flips a reservation flag, returning false if another tick already did
+func claimReservation(db *gorm.DB, reservation models.Reservation, column string) bool {
+	result := db.Model(&models.Reservation{}).Where("id = ? AND "+column+" = ?", reservation.ID, false).Update(column, true)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// releaseReservation clears a claimed flag so the next tick tries again
+func releaseReservation(db *gorm.DB, reservation models.Reservation, column string) {
+	db.Model(&models.Reservation{}).Where("id = ?", reservation.ID).Update(column, false)
+}
+
+// startReservedApp starts the app on behalf of the reservation holder
+func startReservedApp(db *gorm.DB, reservation models.Reservation) {
+	var app models.App
+	if err := db.First(&app, "id = ?", reservation.AppID).Error; err != nil || app.Status == "running" {
+		return
+	}
+	server, err := GetServerByID(db, app.ServerID)
+	if err != nil {
+		log.Printf("Reservation %s: server not found for app %s", reservation.ID, app.Name)
+		return
+	}
+
+	// With auto-stop the timer ends with the window, otherwise the app's usual timeout applies
+	timeout := app.AutoStopTimeout
+	if reservation.AutoStop {
+		timeout = int(time.Until(reservation.EndsAt).Minutes()) + 1
+	}
+
+	actor := Actor{UserID: reservation.UserID, Username: reservation.Username}
+	if _, err := StartApp(db, server, &app, actor, "reservation_start_app", timeout); err != nil {
+		log.Printf("Reservation %s: failed to start app %s: %v", reservation.ID, app.Name, err)
+	}
+}
+
+// stopReservedApp stops the app when the reservation window ends
+func stopReservedApp(db *gorm.DB, reservation models.Reservation) {
+	var app models.App
+	if err := db.First(&app, "id = ?", reservation.AppID).Error; err != nil || app.Status != "running" {
+		return
+	}
+	// A following reservation of the same holder keeps the app running
+	if next := ActiveReservation(db, app.ID, time.Now()); next != nil && next.UserID == reservation.UserID {
+		return
+	}
+	if dependents, _ := RunningDependents(db, app.ID); len(dependents) > 0 {
+		log.Printf("Reservation %s: app %s left running, required by %s", reservation.ID, app.Name, DependentNames(dependents))
+		return
+	}
+	server, err := GetServerByID(db, app.ServerID)
+	if err != nil {
+		log.Printf("Reservation %s: server not found for app %s", reservation.ID, app.Name)
+		return
+	}
+	if _, err := StopApp(db, server, &app, SystemActor("reservation"), "reservation_stop_app", ""); err != nil {
+		log.Printf("Reservation %s: failed to stop app %s: %v", reservation.ID, app.Name, err)
+	}
+}
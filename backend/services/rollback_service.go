@@ -0,0 +1,191 @@
+package services
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// pinFileName is the compose override written by a rollback to pin service images
+const pinFileName = "docker-compose.webmanager-pin.yml"
+
+// ErrNoRollbackTarget is returned when no earlier deployment has different images
+var ErrNoRollbackTarget = errors.New("no earlier deployment with different images")
+
+// composeFileArgs returns the -f flags of a pinned app: its default compose
+// files plus the pin override. Unpinned apps use docker-compose's defaults.
+func composeFileArgs(app models.App) string {
+	if app.PinnedDeploymentID == nil {
+		return ""
+	}
+	var base, overrides []string
+	for _, name := range composeFileNames {
+		if isOverrideFile(name) {
+			overrides = append(overrides, name)
+		} else {
+			base = append(base, name)
+		}
+	}
+	// Passing -f turns off the default lookup, so pick the first existing file of each kind
+	pick := func(names []string) string {
+		return fmt.Sprintf(`$(for f in %s; do [ -f "$f" ] && echo "-f $f" && break; done)`, strings.Join(names, " "))
+	}
+	return " " + pick(base) + " " + pick(overrides) + " -f " + pinFileName
+}
+
+// recordDeployment stores the images an app runs after a start, redeploy or rollback
+func recordDeployment(db *gorm.DB, app models.App, action string, images []models.ServiceImage, rolledBackTo *string, actor Actor) {
+	deployment := models.Deployment{
+		AppID:          app.ID,
+		Action:         action,
+		Images:         images,
+		RolledBackToID: rolledBackTo,
+		Username:       actor.Username,
+	}
+	if actor.UserID != "" {
+		deployment.UserID = &actor.UserID
+	}
+	if err := db.Create(&deployment).Error; err != nil {
+		log.Printf("Could not record deployment of app %s: %v", app.Name, err)
+	}
+}
+
+// recordCurrentDeployment inspects the running containers and records them
+func recordCurrentDeployment(db *gorm.DB, server models.Server, app models.App, action string, actor Actor) {
+	images, err := ComposeImages(server, app)
+	if err != nil {
+		log.Printf("Could not record deployment of app %s: %v", app.Name, err)
+		return
+	}
+	recordDeployment(db, app, action, images, nil, actor)
+}
+
+// imageSet summarises the images of a deployment for comparison
+func imageSet(images []models.ServiceImage) string {
+	versions := make([]string, len(images))
+	for i, image := range images {
+		versions[i] = image.Service + "=" + imageVersion(image)
+	}
+	return strings.Join(versions, ",")
+}
+
+// rollbackTarget returns the deployment to roll back to: the given one, or
+// the latest deployment whose images differ from the most recent one
+func rollbackTarget(db *gorm.DB, app models.App, deploymentID string) (models.Deployment, error) {
+	var target models.Deployment
+	if deploymentID != "" {
+		err := db.Where("id = ? AND app_id = ?", deploymentID, app.ID).First(&target).Error
+		return target, err
+	}
+
+	var deployments []models.Deployment
+	if err := db.Where("app_id = ?", app.ID).Order("created_at desc").Limit(100).Find(&deployments).Error; err != nil {
+		return target, err
+	}
+	if len(deployments) == 0 {
+		return target, ErrNoRollbackTarget
+	}
+	current := imageSet(deployments[0].Images)
+	for _, deployment := range deployments[1:] {
+		if len(deployment.Images) > 0 && imageSet(deployment.Images) != current {
+			return deployment, nil
+		}
+	}
+	return target, ErrNoRollbackTarget
+}
+
+// renderPinFile returns a compose override that pins each service to the
+// exact image of a deployment
+func renderPinFile(deployment models.Deployment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by WebManager: images pinned to deployment %s of %s.\n", deployment.ID, deployment.CreatedAt.Format(time.RFC3339))
+	b.WriteString("# Removed on the next redeploy.\nservices:\n")
+	for _, image := range deployment.Images {
+		ref := image.Digest
+		if ref == "" {
+			ref = image.ImageID
+		}
+		fmt.Fprintf(&b, "  %s:\n    image: %q\n", image.Service, ref)
+	}
+	return b.String()
+}
+
+// RollbackResult is the outcome of a rollback
+type RollbackResult struct {
+	Output     string               `json:"output"`
+	Deployment models.Deployment    `json:"deployment"` // The deployment rolled back to
+	Services   []ServiceImageChange `json:"services"`
+}
+
+// RollbackApp pins the app's images to an earlier deployment through a
+// generated compose override and restarts the stack if it is running.
+// A stopped app uses the pinned images on its next start.
+func RollbackApp(db *gorm.DB, server models.Server, app *models.App, deploymentID string, actor Actor) (RollbackResult, error) {
+	target, err := rollbackTarget(db, *app, deploymentID)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+	result := RollbackResult{Deployment: target}
+
+	cmd := fmt.Sprintf("cd %s && cat > %s", app.ComposePath, pinFileName)
+	if out, err := runSSHWithInput(server, cmd, renderPinFile(target)); err != nil {
+		return result, fmt.Errorf("could not write %s: %v: %s", pinFileName, err, strings.TrimSpace(out))
+	}
+	// Store the pin right away so the database agrees with the server even if
+	// restarting the stack fails; the next start or redeploy then sees the file
+	if err := db.Model(app).Update("pinned_deployment_id", target.ID).Error; err != nil {
+		runSSH(server, fmt.Sprintf("rm -f %s/%s", strings.TrimRight(app.ComposePath, "/"), pinFileName))
+		return result, err
+	}
+	app.PinnedDeploymentID = &target.ID
+
+	if app.Status == "running" {
+		before, err := ComposeImages(server, *app)
+		if err != nil {
+			return result, err
+		}
+		cmd := fmt.Sprintf("cd %s && docker-compose%s up -d --remove-orphans 2>&1", app.ComposePath, composeFileArgs(*app))
+		result.Output, err = runSSH(server, cmd)
+		if err != nil {
+			return result, fmt.Errorf("command failed: %w", err)
+		}
+		after, err := ComposeImages(server, *app)
+		if err != nil {
+			return result, err
+		}
+		result.Services = compareImages(before, after)
+		recordDeployment(db, *app, "rollback", after, &target.ID, actor)
+		defer beginHealthCheck(db, app, actor)
+	}
+
+	setLastAction(app, "rollback_app", actor, time.Now())
+	db.Save(app)
+
+	details := fmt.Sprintf("Pinned images of deployment %s (%s)", target.ID, target.CreatedAt.Format(time.RFC3339))
+	if result.Services != nil {
+		details += "; " + strings.TrimPrefix(redeployDetails(result.Services), "Redeployed: ")
+	} else {
+		details += ", applied on next start"
+	}
+	LogActionAs(db, actor, "rollback_app", "app", app.ID, app.Name, details)
+	EmitAppEvent(db, EventAppRolledBack, *app, actor)
+
+	return result, nil
+}
+
+// unpinApp removes a rollback pin so the app runs the images of its compose files again
+func unpinApp(db *gorm.DB, server models.Server, app *models.App) error {
+	if app.PinnedDeploymentID == nil {
+		return nil
+	}
+	if _, err := runSSH(server, fmt.Sprintf("rm -f %s/%s", strings.TrimRight(app.ComposePath, "/"), pinFileName)); err != nil {
+		return fmt.Errorf("could not remove %s: %w", pinFileName, err)
+	}
+	app.PinnedDeploymentID = nil
+	return db.Model(app).Update("pinned_deployment_id", nil).Error
+}
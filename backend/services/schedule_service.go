@@ -0,0 +1,159 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseSchedule validates a cron expression and IANA timezone name
+func ParseSchedule(expr, timezone string) (cron.Schedule, *time.Location, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	schedule, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	return schedule, loc, nil
+}
+
+// NextRuns returns the next n firings of a schedule after from, in the schedule's timezone
+func NextRuns(schedule models.AppSchedule, from time.Time, n int) ([]time.Time, error) {
+	sched, loc, err := ParseSchedule(schedule.CronExpr, schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]time.Time, 0, n)
+	next := from.In(loc)
+	for i := 0; i < n; i++ {
+		next = sched.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs, nil
+}
+
+// SetNextRun computes NextRunAt from now, or clears it for disabled schedules
+func SetNextRun(schedule *models.AppSchedule, now time.Time) error {
+	schedule.NextRunAt = nil
+	if !schedule.Enabled {
+		return nil
+	}
+	runs, err := NextRuns(*schedule, now, 1)
+	if err != nil {
+		return err
+	}
+	if len(runs) > 0 {
+		schedule.NextRunAt = &runs[0]
+	}
+	return nil
+}
+
+// StartScheduler runs due app schedules and reservation windows in the background
+func StartScheduler(db *gorm.DB) {
+	log.Printf("App scheduler started")
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			now := time.Now()
+			runDueSchedules(db, now)
+			runDueReservations(db, now)
+		}
+	}()
+}
+
+func runDueSchedules(db *gorm.DB, now time.Time) {
+	var schedules []models.AppSchedule
+	if err := db.Where("enabled = ? AND next_run_at <= ?", true, now).Find(&schedules).Error; err != nil {
+		log.Printf("Scheduler could not load schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		// Missed firings are not replayed: the next run is computed from now
+		previous := *schedule.NextRunAt
+		if err := SetNextRun(&schedule, now); err != nil {
+			log.Printf("Schedule %s disabled: %v", schedule.ID, err)
+			db.Model(&schedule).Updates(map[string]interface{}{"enabled": false, "next_run_at": nil, "last_result": err.Error()})
+			continue
+		}
+
+		// Claim the firing; no rows affected means it was already claimed
+		result := db.Model(&models.AppSchedule{}).
+			Where("id = ? AND next_run_at = ?", schedule.ID, previous).
+			Updates(map[string]interface{}{"next_run_at": schedule.NextRunAt, "last_run_at": now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+
+		schedule := schedule
+		if err := EnqueueJob("schedule "+schedule.ID, func() { runSchedule(db, schedule) }); err != nil {
+			log.Printf("Schedule %s not run: %v", schedule.ID, err)
+			db.Model(&schedule).Update("last_result", "failed: "+err.Error())
+		}
+	}
+}
+
+// runSchedule executes a schedule through the same start/stop path as the API
+func runSchedule(db *gorm.DB, schedule models.AppSchedule) {
+	actor := SystemActor("scheduler")
+	result := "failed: app not found"
+
+	var app models.App
+	if err := db.First(&app, "id = ?", schedule.AppID).Error; err == nil {
+		result = executeSchedule(db, schedule, &app, actor)
+		if result != "started" && result != "stopped" {
+			// Successful runs are audited by StartApp/StopApp
+			details := fmt.Sprintf("Schedule %q (%s) %s: %s", schedule.CronExpr, schedule.Timezone, schedule.Action, result)
+			LogActionAs(db, actor, "scheduled_run", "app", app.ID, app.Name, details)
+		}
+	}
+
+	log.Printf("Schedule %s (%s) for app %s: %s", schedule.ID, schedule.Action, schedule.AppID, result)
+	db.Model(&models.AppSchedule{}).Where("id = ?", schedule.ID).Update("last_result", result)
+}
+
+func executeSchedule(db *gorm.DB, schedule models.AppSchedule, app *models.App, actor Actor) string {
+	server, err := GetServerByID(db, app.ServerID)
+	if err != nil {
+		return "failed: server not found"
+	}
+
+	switch schedule.Action {
+	case "start":
+		if app.Status == "running" {
+			return "skipped: already running"
+		}
+		if _, err := StartApp(db, server, app, actor, "scheduled_start_app", schedule.AutoStopMinutes); err != nil {
+			return "failed: " + err.Error()
+		}
+		return "started"
+	case "stop":
+		if app.Status != "running" {
+			return "skipped: not running"
+		}
+		if reservation := ActiveReservation(db, app.ID, time.Now()); reservation != nil {
+			return "skipped: reserved by " + reservation.Username
+		}
+		if dependents, _ := RunningDependents(db, app.ID); len(dependents) > 0 {
+			return "skipped: required by " + DependentNames(dependents)
+		}
+		if _, err := StopApp(db, server, app, actor, "scheduled_stop_app", ""); err != nil {
+			return "failed: " + err.Error()
+		}
+		return "stopped"
+	default:
+		return fmt.Sprintf("failed: unknown action %q", schedule.Action)
+	}
+}
@@ -2,11 +2,12 @@ package services
 
 import (
 	"backend/models"
-	"backend/utils"
 	"fmt"
 	"log"
 	"net"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // CheckServerStatus tests connectivity to a server
@@ -28,8 +29,8 @@ func CheckServerStatus(server models.Server) (string, error) {
 	conn.Close()
 	
 	// If we have SSH credentials, try SSH connection
-	if server.SSHUser != "" && server.SSHPrivateKey != "" {
-		output, err := utils.RunSSHCommand(server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey, "echo 'connection test'")
+	if hasSSHCredentials(server) {
+		output, err := runSSH(server, "echo 'connection test'")
 		if err != nil {
 			log.Printf("Server %s (%s) SSH connection failed: %v", server.Name, server.Address, err)
 			return "offline", fmt.Errorf("SSH connection failed: %w", err)
@@ -45,7 +46,7 @@ func CheckServerStatus(server models.Server) (string, error) {
 
 // GetRunningContainersCount gets the number of running Docker containers on a server
 func GetRunningContainersCount(server models.Server) (int, error) {
-	output, err := utils.RunSSHCommand(server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey, "docker ps -q | wc -l")
+	output, err := runSSH(server, "docker ps -q | wc -l")
 	if err != nil {
 		return 0, fmt.Errorf("failed to get container count: %w", err)
 	}
@@ -59,6 +60,11 @@ func GetRunningContainersCount(server models.Server) (int, error) {
 	return count, nil
 }
 
+// hasSSHCredentials reports whether WebManager can run commands on a server
+func hasSSHCredentials(server models.Server) bool {
+	return server.SSHUser != "" && server.SSHPrivateKey != ""
+}
+
 // UpdateServerStatus updates a server's status and running apps count
 func UpdateServerStatus(server *models.Server) {
 	status, err := CheckServerStatus(*server)
@@ -71,7 +77,46 @@ func UpdateServerStatus(server *models.Server) {
 		if count, err := GetRunningContainersCount(*server); err == nil {
 			server.RunningAppsCount = count
 		}
+
+		// Collect host resources; keep the previous snapshot if this fails.
+		// Servers only reachable over TCP have no way to report them.
+		if hasSSHCredentials(*server) {
+			if metrics, err := CollectHostMetrics(*server); err == nil {
+				server.Metrics = metrics
+			} else {
+				log.Printf("Server %s (%s) host metrics unavailable: %v", server.Name, server.Address, err)
+			}
+		}
 	} else {
 		server.RunningAppsCount = 0
 	}
-}
\ No newline at end of file
+}
+
+// serverStatusColumns are the columns UpdateServerStatus sets
+var serverStatusColumns = []string{
+	"status", "last_checked",
+	"metrics_cpu_usage", "metrics_load_avg1", "metrics_load_avg5", "metrics_load_avg15",
+	"metrics_mem_total", "metrics_mem_available", "metrics_docker_root_dir",
+	"metrics_disk_total", "metrics_disk_free", "metrics_uptime", "metrics_collected_at",
+}
+
+// RefreshServer checks a server, saves its status and records its metrics
+func RefreshServer(db *gorm.DB, server *models.Server) error {
+	previousStatus := server.Status
+	UpdateServerStatus(server)
+
+	// Only write what the check produced: the row was read before the
+	// (slow) SSH round trips and may have been edited since
+	if err := db.Model(server).Select(serverStatusColumns).Updates(server).Error; err != nil {
+		return err
+	}
+
+	if previousStatus == "online" && server.Status == "offline" {
+		EmitServerEvent(db, EventServerOffline, *server)
+		NotifyServerOffline(db, *server)
+	} else if previousStatus == "offline" && server.Status == "online" {
+		EmitServerEvent(db, EventServerOnline, *server)
+	}
+
+	return RecordServerSamples(db, *server)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"backend/models"
+	"backend/utils"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// TerminalSession is an interactive SSH session with a pseudo-terminal
+type TerminalSession struct {
+	client  *ssh.Client
+	session *ssh.Session
+	stdin   io.WriteCloser
+	Output  io.Reader // Combined stdout and stderr of the remote shell
+	Started time.Time
+}
+
+// TerminalIdleTimeout is how long a terminal may go without input before it is closed
+func TerminalIdleTimeout() time.Duration {
+	return time.Duration(envInt("TERMINAL_IDLE_TIMEOUT_MINUTES", 15)) * time.Minute
+}
+
+// ContainerShellCommand opens bash, or sh if bash is missing, in a service container of an app
+func ContainerShellCommand(app models.App, service string) (string, error) {
+	if !serviceNamePattern.MatchString(service) {
+		return "", fmt.Errorf("invalid service name %q", service)
+	}
+	return fmt.Sprintf(`cd %s && exec docker-compose exec %s sh -c 'if command -v bash >/dev/null; then exec bash; else exec sh; fi'`,
+		app.ComposePath, service), nil
+}
+
+// OpenTerminal starts command on a server with a PTY of the given size,
+// or the login shell if command is empty
+func OpenTerminal(server models.Server, command string, cols, rows int) (*TerminalSession, error) {
+	client, err := utils.DialSSH(server.SSHUser, server.Address, server.SSHPort, server.SSHPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	t := &TerminalSession{client: client, session: session, Started: time.Now()}
+	fail := func(err error) (*TerminalSession, error) {
+		t.Close()
+		return nil, err
+	}
+
+	modes := ssh.TerminalModes{ssh.ECHO: 1, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400}
+	if err := session.RequestPty("xterm-256color", rows, cols, modes); err != nil {
+		return fail(err)
+	}
+	if t.stdin, err = session.StdinPipe(); err != nil {
+		return fail(err)
+	}
+	// With a PTY the remote side merges stderr into stdout
+	if t.Output, err = session.StdoutPipe(); err != nil {
+		return fail(err)
+	}
+
+	if command == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(command)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return t, nil
+}
+
+// Write sends input to the remote shell
+func (t *TerminalSession) Write(p []byte) (int, error) {
+	return t.stdin.Write(p)
+}
+
+// Resize changes the size of the remote PTY
+func (t *TerminalSession) Resize(cols, rows int) error {
+	return t.session.WindowChange(rows, cols)
+}
+
+// Wait blocks until the remote shell exits
+func (t *TerminalSession) Wait() error {
+	return t.session.Wait()
+}
+
+// Close ends the session and the connection
+func (t *TerminalSession) Close() error {
+	t.session.Close()
+	return t.client.Close()
+}
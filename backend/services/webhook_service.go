@@ -0,0 +1,202 @@
+package services
+
+import (
+	"backend/models"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook event names
+const (
+	EventAppStarted     = "app.started"
+	EventAppStopped     = "app.stopped"
+	EventAppAutoStopped = "app.auto_stopped"
+	EventAppRedeployed  = "app.redeployed"
+	EventAppRolledBack  = "app.rolled_back"
+	EventAppHealthy     = "app.healthy"
+	EventAppUnhealthy   = "app.unhealthy"
+	EventServerOffline  = "server.offline"
+	EventServerOnline   = "server.online"
+	EventWebhookTest    = "webhook.test"
+)
+
+// WebhookEvents lists the events a webhook can subscribe to
+var WebhookEvents = []string{
+	EventAppStarted, EventAppStopped, EventAppAutoStopped, EventAppRedeployed, EventAppRolledBack, EventAppHealthy, EventAppUnhealthy,
+	EventServerOffline, EventServerOnline,
+}
+
+// WebhookPayload is the JSON body posted to webhook URLs
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookSubscribes reports whether a webhook's event filter matches an event
+func WebhookSubscribes(webhook models.Webhook, event string) bool {
+	if event == EventWebhookTest {
+		return true
+	}
+	for _, e := range strings.Split(webhook.Events, ",") {
+		e = strings.TrimSpace(e)
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// EmitEvent delivers an event to every active webhook subscribed to it.
+// Deliveries run in the background and are retried with exponential backoff.
+func EmitEvent(db *gorm.DB, event string, data interface{}) {
+	var webhooks []models.Webhook
+	if err := db.Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		log.Printf("Could not load webhooks for %s: %v", event, err)
+		return
+	}
+
+	payload := WebhookPayload{ID: newEventID(), Event: event, Timestamp: time.Now().UTC(), Data: data}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Could not encode %s event: %v", event, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if WebhookSubscribes(webhook, event) {
+			go deliverWithRetry(db, webhook, payload, body)
+		}
+	}
+}
+
+// EmitAppEvent emits an app lifecycle event with the app and acting user
+func EmitAppEvent(db *gorm.DB, event string, app models.App, actor Actor) {
+	EmitEvent(db, event, map[string]interface{}{
+		"app": map[string]interface{}{
+			"id":          app.ID,
+			"name":        app.Name,
+			"domain":      app.Domain,
+			"projectId":   app.ProjectID,
+			"serverId":    app.ServerID,
+			"status":      app.Status,
+			"startedAt":   app.StartedAt,
+			"timerEndsAt": app.TimerEndsAt,
+		},
+		"actor": actor.Username,
+	})
+}
+
+// EmitServerEvent emits a server status change event
+func EmitServerEvent(db *gorm.DB, event string, server models.Server) {
+	EmitEvent(db, event, map[string]interface{}{
+		"server": map[string]interface{}{
+			"id":          server.ID,
+			"name":        server.Name,
+			"address":     server.Address,
+			"status":      server.Status,
+			"lastChecked": server.LastChecked,
+		},
+	})
+}
+
+// SendTestEvent delivers a single test event synchronously and returns the delivery record
+func SendTestEvent(db *gorm.DB, webhook models.Webhook) models.WebhookDelivery {
+	payload := WebhookPayload{
+		ID:        newEventID(),
+		Event:     EventWebhookTest,
+		Timestamp: time.Now().UTC(),
+		Data:      map[string]interface{}{"webhook": webhook.Name, "message": "Test event from WebManager"},
+	}
+	body, _ := json.Marshal(payload)
+	return deliverOnce(db, webhook, payload, body, 1)
+}
+
+func deliverWithRetry(db *gorm.DB, webhook models.Webhook, payload WebhookPayload, body []byte) {
+	maxAttempts := envInt("WEBHOOK_MAX_ATTEMPTS", 5)
+	backoff := time.Duration(envInt("WEBHOOK_RETRY_BASE_SECONDS", 2)) * time.Second
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if deliverOnce(db, webhook, payload, body, attempt).Success {
+			return
+		}
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	log.Printf("Webhook %s gave up on %s after %d attempts", webhook.Name, payload.Event, maxAttempts)
+}
+
+// deliverOnce posts the signed payload and records the attempt in the delivery log
+func deliverOnce(db *gorm.DB, webhook models.Webhook, payload WebhookPayload, body []byte, attempt int) models.WebhookDelivery {
+	delivery := models.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   payload.ID,
+		Event:     payload.Event,
+		Payload:   string(body),
+		Attempt:   attempt,
+	}
+
+	start := time.Now()
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "WebManager-Webhook")
+		req.Header.Set("X-WebManager-Event", payload.Event)
+		req.Header.Set("X-WebManager-Delivery", payload.ID)
+		if webhook.Secret != "" {
+			req.Header.Set("X-WebManager-Signature", "sha256="+SignWebhookPayload(webhook.Secret, body))
+		}
+
+		var resp *http.Response
+		resp, err = webhookClient.Do(req)
+		if err == nil {
+			respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			delivery.StatusCode = resp.StatusCode
+			delivery.ResponseBody = string(respBody)
+			delivery.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+			if !delivery.Success {
+				delivery.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+			}
+		}
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	delivery.DurationMs = time.Since(start).Milliseconds()
+
+	if err := db.Create(&delivery).Error; err != nil {
+		log.Printf("Could not record delivery of %s to webhook %s: %v", payload.Event, webhook.Name, err)
+	}
+	return delivery
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of body keyed with secret
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffCells bounds the LCS table; larger inputs are diffed as a full replacement
+const maxDiffCells = 4_000_000
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// UnifiedDiff returns a unified diff between two texts, or "" if they are equal
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	return MaskedUnifiedDiff(oldName, newName, oldText, newText, nil)
+}
+
+// MaskedUnifiedDiff is UnifiedDiff with the lines of both texts passed through
+// mask before they are printed. Lines are still compared unmasked, so a change
+// hidden by mask shows up as a changed line. mask must return as many lines as
+// it is given; nil leaves the lines as they are.
+func MaskedUnifiedDiff(oldName, newName, oldText, newText string, mask func(lines []string) []string) string {
+	if oldText == newText {
+		return ""
+	}
+	oldLines, newLines := splitLines(oldText), splitLines(newText)
+	showOld, showNew := oldLines, newLines
+	if mask != nil {
+		showOld, showNew = mask(oldLines), mask(newLines)
+	}
+	lines := diffLines(oldLines, newLines, showOld, showNew)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Group changes into hunks with up to diffContext lines of context
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+		start := max(i-diffContext, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].op == ' ' {
+				next++
+			}
+			if next == len(lines) || next-end > 2*diffContext {
+				end = min(end+diffContext, len(lines))
+				break
+			}
+			end = next
+		}
+
+		oldStart, newStart := 1, 1
+		for _, l := range lines[:start] {
+			if l.op != '+' {
+				oldStart++
+			}
+			if l.op != '-' {
+				newStart++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, l := range lines[start:end] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+		// An empty range is numbered after the line it follows, as diff(1) does
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, l := range lines[start:end] {
+			b.WriteByte(l.op)
+			b.WriteString(l.text)
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+	return b.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a line diff of a and b from the longest common
+// subsequence, taking the text of each line from showA and showB
+func diffLines(a, b, showA, showB []string) []diffLine {
+	if len(a)*len(b) > maxDiffCells {
+		lines := make([]diffLine, 0, len(a)+len(b))
+		for i := range a {
+			lines = append(lines, diffLine{'-', showA[i]})
+		}
+		for i := range b {
+			lines = append(lines, diffLine{'+', showB[i]})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', showB[j]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', showA[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', showB[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', showA[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', showB[j]})
+	}
+	return lines
+}
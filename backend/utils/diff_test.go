@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"change in the middle",
+			"a\nb\nc\n", "a\nB\nc\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"new file",
+			"", "a\nb\n",
+			"--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			"deleted content",
+			"a\nb\n", "",
+			"--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			"append",
+			"a\nb\n", "a\nb\nc\n",
+			"--- old\n+++ new\n@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+		},
+		{
+			"context is limited to three lines",
+			"1\n2\n3\n4\n5\n6\n7\n8\n", "1\n2\n3\n4\nX\n6\n7\n8\n",
+			"--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+X\n 6\n 7\n 8\n",
+		},
+		{
+			"distant changes form separate hunks",
+			"a\n1\n2\n3\n4\n5\n6\n7\nb\n", "A\n1\n2\n3\n4\n5\n6\n7\nB\n",
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+		{
+			"close changes share a hunk",
+			"a\n1\n2\nb\n", "A\n1\n2\nB\n",
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n-b\n+B\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("old", "new", tt.old, tt.new); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskedUnifiedDiff(t *testing.T) {
+	mask := func(lines []string) []string {
+		masked := make([]string, len(lines))
+		for i, line := range lines {
+			if key, _, found := strings.Cut(line, "="); found {
+				line = key + "=***"
+			}
+			masked[i] = line
+		}
+		return masked
+	}
+	old := "a\nKEY=old\nb\n"
+	new := "a\nKEY=new\nb\n"
+
+	want := "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-KEY=***\n+KEY=***\n b\n"
+	if got := MaskedUnifiedDiff("old", "new", old, new, mask); got != want {
+		t.Errorf("MaskedUnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := MaskedUnifiedDiff("old", "new", old, old, mask); got != "" {
+		t.Errorf("MaskedUnifiedDiff() of equal texts = %q, want \"\"", got)
+	}
+}
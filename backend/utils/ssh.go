@@ -2,14 +2,19 @@ package utils
 
 import (
     "golang.org/x/crypto/ssh"
+    "bufio"
+    "context"
     "fmt"
+    "io"
+    "strings"
     "time"
 )
 
-func RunSSHCommand(user, addr string, port int, privKey string, cmd string) (string, error) {
+// DialSSH opens an SSH connection; the caller must close it
+func DialSSH(user, addr string, port int, privKey string) (*ssh.Client, error) {
     signer, err := ssh.ParsePrivateKey([]byte(privKey))
     if err != nil {
-        return "", err
+        return nil, err
     }
     config := &ssh.ClientConfig{
         User: user,
@@ -17,7 +22,29 @@ func RunSSHCommand(user, addr string, port int, privKey string, cmd string) (str
         HostKeyCallback: ssh.InsecureIgnoreHostKey(),
         Timeout: 10 * time.Second,
     }
-    client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", addr, port), config)
+    return ssh.Dial("tcp", fmt.Sprintf("%s:%d", addr, port), config)
+}
+
+func RunSSHCommand(user, addr string, port int, privKey string, cmd string) (string, error) {
+    client, err := DialSSH(user, addr, port, privKey)
+    if err != nil {
+        return "", err
+    }
+    defer client.Close()
+
+    session, err := client.NewSession()
+    if err != nil {
+        return "", err
+    }
+    defer session.Close()
+
+    out, err := session.CombinedOutput(cmd)
+    return string(out), err
+}
+
+// RunSSHCommandWithInput runs cmd like RunSSHCommand, feeding input to its stdin
+func RunSSHCommandWithInput(user, addr string, port int, privKey string, cmd string, input string) (string, error) {
+    client, err := DialSSH(user, addr, port, privKey)
     if err != nil {
         return "", err
     }
@@ -29,6 +56,54 @@ func RunSSHCommand(user, addr string, port int, privKey string, cmd string) (str
     }
     defer session.Close()
 
+    session.Stdin = strings.NewReader(input)
     out, err := session.CombinedOutput(cmd)
     return string(out), err
 }
+
+// StreamSSHCommand runs cmd and calls onLine for each line of its combined
+// output until the command exits or ctx is cancelled
+func StreamSSHCommand(ctx context.Context, user, addr string, port int, privKey string, cmd string, onLine func(line string)) error {
+    client, err := DialSSH(user, addr, port, privKey)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+
+    session, err := client.NewSession()
+    if err != nil {
+        return err
+    }
+    defer session.Close()
+
+    reader, writer := io.Pipe()
+    session.Stdout = writer
+    session.Stderr = writer
+    if err := session.Start(cmd); err != nil {
+        return err
+    }
+    go func() {
+        writer.CloseWithError(session.Wait())
+    }()
+
+    // Closing the connection ends the remote command and unblocks the scanner
+    done := make(chan struct{})
+    defer close(done)
+    go func() {
+        select {
+        case <-ctx.Done():
+            client.Close()
+        case <-done:
+        }
+    }()
+
+    scanner := bufio.NewScanner(reader)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        onLine(scanner.Text())
+    }
+    if ctx.Err() != nil {
+        return nil
+    }
+    return scanner.Err()
+}